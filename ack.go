@@ -0,0 +1,335 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Number of bytes at the beginning of a file that identify it in a
+// Checkpoint.
+const fingerprintSize = 1024
+
+// Minimum time between the saves of the checkpoint of a running tail.
+const checkpointInterval = time.Second
+
+// Checkpoint is the committed position of a tail: the offset following the
+// lines acknowledged so far, in the file identified by its first bytes.
+type Checkpoint struct {
+	Offset          int64
+	Fingerprint     string // Hex SHA-256 of the first FingerprintSize bytes of the file
+	FingerprintSize int64  // Less than 1024 if the file was shorter when opened
+}
+
+// Checkpointer stores the checkpoints of tails, see Config.Checkpointer.
+type Checkpointer interface {
+	// Load returns the checkpoint saved for filename, or nil if there is none.
+	Load(filename string) (*Checkpoint, error)
+	// Save is called once the committed position of filename advances, at
+	// most every second while the tail runs, and when it stops.
+	Save(filename string, c Checkpoint) error
+}
+
+// acker tracks the lines delivered with an ack handle, in delivery order, to
+// commit the position following the acknowledged lines.
+type acker struct {
+	tail *Tail
+	key  string // Of the checkpoints: Filename, or the pattern of TailLatest or the template
+
+	mu        sync.Mutex
+	pending   []*ackEntry
+	committed Checkpoint
+	current   Checkpoint // Of the open file, following the last complete line
+
+	saveLk    sync.Mutex  // Saves the checkpoints in order
+	saved     time.Time   // Of the last save
+	saveTimer *time.Timer // Of the next save, once the position advanced
+	stopped   bool        // Once the tail stopped, positions are saved right away
+}
+
+type ackEntry struct {
+	acker      *acker
+	checkpoint Checkpoint // Committed once this entry and the ones before are acked
+	acked      bool
+}
+
+// Ack acknowledges that the line was processed. Once all the lines before
+// were acknowledged too, the position following the line is committed, see
+// Tail.Committed. Ack does nothing without Config.Ack, for lines not read
+// from the file and when called again.
+func (line *Line) Ack() {
+	if line.ack != nil {
+		line.ack.ack()
+	}
+}
+
+func newAcker(tail *Tail) *acker {
+	return &acker{tail: tail, key: tail.checkpointKey()}
+}
+
+// track returns the ack handle of a line delivered before offset. Partial
+// lines do not advance the committed position, their whole line does.
+func (a *acker) track(offset int64, partial bool) *ackEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !partial {
+		a.current.Offset = offset
+		if f := a.tail.file; f != nil && a.tail.rotated == nil && a.current.FingerprintSize < fingerprintSize && offset > a.current.FingerprintSize {
+			// The file was shorter than the fingerprint when opened.
+			if fp, err := fingerprint(f, fingerprintSize); err == nil {
+				fp.Offset = offset
+				a.current = fp
+			}
+		}
+	}
+	e := &ackEntry{acker: a, checkpoint: a.current}
+	a.pending = append(a.pending, e)
+	return e
+}
+
+// skip commits offset once the lines before are acknowledged, for the bytes
+// that are not delivered.
+func (a *acker) skip(offset int64) {
+	a.track(offset, false).ack()
+}
+
+// opened tells that the file was (re)opened, with its position at offset.
+func (a *acker) opened(f *os.File, offset int64) {
+	fp, err := fingerprint(f, fingerprintSize)
+	if err != nil {
+		a.tail.Logger.Printf("Unable to fingerprint %s: %s", a.tail.name, err)
+	}
+	a.start(fp, offset)
+}
+
+// start tells that the file of fingerprint fp is read from offset.
+func (a *acker) start(fp Checkpoint, offset int64) {
+	a.mu.Lock()
+	a.current = fp
+	a.mu.Unlock()
+	a.skip(offset)
+}
+
+func (e *ackEntry) ack() {
+	a := e.acker
+	a.mu.Lock()
+	if e.acked {
+		a.mu.Unlock()
+		return
+	}
+	e.acked = true
+	advanced := false
+	for len(a.pending) > 0 && a.pending[0].acked {
+		advanced = advanced || a.pending[0].checkpoint != a.committed
+		a.committed = a.pending[0].checkpoint
+		a.pending[0] = nil
+		a.pending = a.pending[1:]
+	}
+	a.mu.Unlock()
+
+	if advanced && a.tail.Checkpointer != nil {
+		a.scheduleSave()
+	}
+}
+
+// scheduleSave saves the committed position, at most every
+// checkpointInterval while the tail runs.
+func (a *acker) scheduleSave() {
+	a.mu.Lock()
+	if a.saveTimer != nil {
+		// The save to come saves this position too.
+		a.mu.Unlock()
+		return
+	}
+	if wait := time.Until(a.saved.Add(checkpointInterval)); !a.stopped && wait > 0 {
+		a.saveTimer = time.AfterFunc(wait, a.save)
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+	a.save()
+}
+
+// save saves the committed position.
+func (a *acker) save() {
+	a.saveLk.Lock()
+	defer a.saveLk.Unlock()
+	a.mu.Lock()
+	c := a.committed
+	a.saved = time.Now()
+	a.saveTimer = nil
+	a.mu.Unlock()
+	if err := a.tail.Checkpointer.Save(a.key, c); err != nil {
+		a.tail.Logger.Printf("Unable to save the checkpoint of %s: %s", a.key, err)
+	}
+}
+
+// stop saves the committed position that is waiting for the next save, once
+// the tail stops.
+func (a *acker) stop() {
+	a.mu.Lock()
+	a.stopped = true
+	waiting := a.saveTimer != nil && a.saveTimer.Stop()
+	a.mu.Unlock()
+	if waiting {
+		a.save()
+	}
+}
+
+// Committed returns the offset following the lines acknowledged so far, in
+// the file opened last, see Config.Ack. Lines after it are read again when
+// tailing resumes from the Checkpointer.
+func (tail *Tail) Committed() int64 {
+	if tail.acker == nil {
+		return 0
+	}
+	tail.acker.mu.Lock()
+	defer tail.acker.mu.Unlock()
+	return tail.acker.committed.Offset
+}
+
+// checkpointKey returns the name the checkpoints of the file are saved
+// under: Filename, or the pattern of TailLatest or the template, since
+// the file read changes.
+func (tail *Tail) checkpointKey() string {
+	if tail.latest != nil {
+		return tail.latest.pattern
+	}
+	if tail.template != "" {
+		return tail.template
+	}
+	return tail.Filename
+}
+
+// restoreCheckpoint sets Location to the checkpoint of the file saved in cp
+// under key, if it has one and it is still the same file.
+func (tail *Tail) restoreCheckpoint(cp Checkpointer, key string) error {
+	c, err := cp.Load(key)
+	if err != nil || c == nil {
+		return err
+	}
+	fp, err := fingerprint(tail.file, c.FingerprintSize)
+	if err != nil {
+		return err
+	}
+	fi, err := tail.file.Stat()
+	if err != nil {
+		return err
+	}
+	if fp.FingerprintSize != c.FingerprintSize || fp.Fingerprint != c.Fingerprint || c.Offset > fi.Size() {
+		if tail.Rotation != nil {
+			found, err := tail.catchUp(c)
+			if err != nil || found {
+				return err
+			}
+		}
+		tail.Logger.Printf("The checkpoint of %s is for another file, reading it from the beginning", tail.name)
+		return nil
+	}
+	tail.Location = &SeekInfo{Offset: c.Offset, Whence: io.SeekStart}
+	return nil
+}
+
+// fingerprint returns the fingerprint of the first size bytes of f, or of
+// all of them if f is shorter.
+func fingerprint(f *os.File, size int64) (Checkpoint, error) {
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return Checkpoint{}, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return Checkpoint{Fingerprint: hex.EncodeToString(sum[:]), FingerprintSize: int64(n)}, nil
+}
+
+// FileCheckpointer is a Checkpointer keeping the checkpoints of all the tails
+// in a JSON file, replaced on each save.
+type FileCheckpointer struct {
+	path        string
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewFileCheckpointer returns a FileCheckpointer keeping the checkpoints in
+// the file at path.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Load returns the checkpoint saved for filename, or nil.
+func (fc *FileCheckpointer) Load(filename string) (*Checkpoint, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if err := fc.load(); err != nil {
+		return nil, err
+	}
+	c, ok := fc.checkpoints[filename]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// Save saves the checkpoint of filename with the others.
+func (fc *FileCheckpointer) Save(filename string, c Checkpoint) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if err := fc.load(); err != nil {
+		return err
+	}
+	fc.checkpoints[filename] = c
+	data, err := json.Marshal(fc.checkpoints)
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file, synced to disk, and rename it, so that the file
+	// is never left half written, even by a crash.
+	tmp, err := ioutil.TempFile(filepath.Dir(fc.path), filepath.Base(fc.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), fc.path)
+}
+
+func (fc *FileCheckpointer) load() error {
+	if fc.checkpoints != nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(fc.path)
+	if os.IsNotExist(err) {
+		fc.checkpoints = map[string]Checkpoint{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	checkpoints := map[string]Checkpoint{}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return err
+	}
+	fc.checkpoints = checkpoints
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readAll(tail *Tail) []*Line {
+	var lines []*Line
+	for line := range tail.Lines {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAck(t *testing.T) {
+	tailTest, cleanup := NewTailTest("ack", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	tail := tailTest.StartTail("test.txt", Config{Ack: true, Logger: DiscardingLogger})
+	lines := readAll(tail)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+
+	lines[1].Ack()
+	if c := tail.Committed(); c != 0 {
+		t.Errorf("expected nothing committed before the first line is acked, got %d", c)
+	}
+	lines[0].Ack()
+	if c := tail.Committed(); c != 12 {
+		t.Errorf("expected 12 committed, got %d", c)
+	}
+	lines[2].Ack()
+	lines[2].Ack()
+	if c := tail.Committed(); c != 18 {
+		t.Errorf("expected 18 committed, got %d", c)
+	}
+}
+
+func TestAckPartial(t *testing.T) {
+	tailTest, cleanup := NewTailTest("ack-partial", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello world\nbye\n")
+	tail := tailTest.StartTail("test.txt", Config{Ack: true, MaxLineSize: 5, Logger: DiscardingLogger})
+	lines := readAll(tail)
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+
+	lines[0].Ack()
+	lines[1].Ack()
+	if c := tail.Committed(); c != 0 {
+		t.Errorf("expected nothing committed for the parts of a line, got %d", c)
+	}
+	lines[2].Ack()
+	if c := tail.Committed(); c != 12 {
+		t.Errorf("expected 12 committed, got %d", c)
+	}
+}
+
+func TestCheckpointer(t *testing.T) {
+	tailTest, cleanup := NewTailTest("checkpointer", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	checkpoints := tailTest.path + "/checkpoints.json"
+	config := Config{Ack: true, Checkpointer: NewFileCheckpointer(checkpoints), Logger: DiscardingLogger}
+
+	tail := tailTest.StartTail("test.txt", config)
+	lines := readAll(tail)
+	lines[0].Ack()
+	lines[1].Ack()
+
+	// The line that was not acked is read again, from a new Checkpointer.
+	config.Checkpointer = NewFileCheckpointer(checkpoints)
+	tail = tailTest.StartTail("test.txt", config)
+	lines = readAll(tail)
+	if len(lines) != 1 || lines[0].Text != "again" {
+		t.Fatalf("expected the unacked line, got %d lines", len(lines))
+	}
+	lines[0].Ack()
+
+	// Another file is read from the beginning.
+	tailTest.CreateFile("test.txt", "other\n")
+	tail = tailTest.StartTail("test.txt", config)
+	lines = readAll(tail)
+	if len(lines) != 1 || lines[0].Text != "other" {
+		t.Fatalf("expected the line of the new file, got %d lines", len(lines))
+	}
+	lines[0].Ack()
+	c, err := config.Checkpointer.Load(tailTest.path + "/test.txt")
+	if err != nil || c == nil || c.Offset != 6 || c.FingerprintSize != 6 {
+		t.Errorf("unexpected checkpoint %+v (%v)", c, err)
+	}
+}
+
+// countingCheckpointer counts the saves of the checkpoints it keeps.
+type countingCheckpointer struct {
+	mu    sync.Mutex
+	saves int
+	last  Checkpoint
+}
+
+func (c *countingCheckpointer) Load(filename string) (*Checkpoint, error) {
+	return nil, nil
+}
+
+func (c *countingCheckpointer) Save(filename string, checkpoint Checkpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saves++
+	c.last = checkpoint
+	return nil
+}
+
+func TestCheckpointerInterval(t *testing.T) {
+	tailTest, cleanup := NewTailTest("checkpointer-interval", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", strings.Repeat("line\n", 100))
+	cp := &countingCheckpointer{}
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, Ack: true, Checkpointer: cp, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+
+	// The checkpoint is not saved for each line while the tail runs.
+	for i := 0; i < 100; i++ {
+		(<-tail.Lines).Ack()
+	}
+	cp.mu.Lock()
+	saves := cp.saves
+	cp.mu.Unlock()
+	if saves > 2 {
+		t.Errorf("expected at most 2 saves, got %d", saves)
+	}
+
+	// The last position is saved when the tail stops.
+	tail.Stop()
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.last.Offset != 500 {
+		t.Errorf("expected 500 saved, got %d", cp.last.Offset)
+	}
+}
+
+func TestCheckpointerReopen(t *testing.T) {
+	tailTest, cleanup := NewTailTest("checkpointer-reopen", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\n")
+	config := Config{
+		Follow:       true,
+		Ack:          true,
+		Checkpointer: NewFileCheckpointer(tailTest.path + "/checkpoints.json"),
+		Logger:       DiscardingLogger,
+	}
+	tail := tailTest.StartTail("test.txt", config)
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	first := <-tail.Lines
+	<-tail.Lines
+	<-time.After(100 * time.Millisecond)
+	tailTest.TruncateFile("test.txt", "a\n")
+	truncated := <-tail.Lines
+
+	// Lines of the previous file are committed first.
+	truncated.Ack()
+	first.Ack()
+	if c := tail.Committed(); c != 6 {
+		t.Errorf("expected 6 committed, got %d", c)
+	}
+}
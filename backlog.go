@@ -0,0 +1,72 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// SkipError is the Err of the line delivered when the beginning of a file is
+// skipped because of Config.IgnoreOlderThan or Config.MaxBacklogBytes. The
+// text of the line is the message of the error.
+type SkipError struct {
+	Bytes  int64  // Bytes skipped
+	Reason string // "ignore older" or "max backlog"
+}
+
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("skipped %d bytes (%s)", e.Bytes, e.Reason)
+}
+
+// skipBacklog applies IgnoreOlderThan and MaxBacklogBytes to the file just
+// opened, read from offset. It returns the offset to read from.
+func (tail *Tail) skipBacklog(offset int64) int64 {
+	if tail.Pipe || (tail.IgnoreOlderThan <= 0 && tail.MaxBacklogBytes <= 0) {
+		return offset
+	}
+	fi, err := tail.file.Stat()
+	if err != nil {
+		tail.Logger.Printf("Unable to stat %s: %s", tail.name, err)
+		return offset
+	}
+	size := fi.Size()
+	if size <= offset {
+		return offset
+	}
+
+	to := offset
+	var reason string
+	if tail.IgnoreOlderThan > 0 && time.Since(fi.ModTime()) > tail.IgnoreOlderThan {
+		to, reason = size, "ignore older"
+	} else if tail.MaxBacklogBytes > 0 && size-offset > tail.MaxBacklogBytes {
+		// Start with the first whole line within the limit.
+		if to, err = nextLineStart(tail.file, size-tail.MaxBacklogBytes, size); err != nil {
+			tail.Logger.Printf("Unable to read %s: %s", tail.name, err)
+			return offset
+		}
+		reason = "max backlog"
+	}
+	if to == offset {
+		return offset
+	}
+	if _, err := tail.file.Seek(to, io.SeekStart); err != nil {
+		tail.Logger.Printf("Seek error on %s: %s", tail.name, err)
+		return offset
+	}
+
+	skipErr := &SkipError{Bytes: to - offset, Reason: reason}
+	tail.updateStats(func(s *Stats) {
+		s.SkippedBytes += skipErr.Bytes
+		s.Offset = to
+	})
+	tail.deliver(&Line{
+		Text:     skipErr.Error(),
+		Num:      tail.lineNum,
+		SeekInfo: SeekInfo{Offset: to},
+		Time:     time.Now(),
+		Err:      skipErr,
+	})
+	return to
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMaxBacklogBytes(t *testing.T) {
+	tailTest, cleanup := NewTailTest("max-backlog", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	tail := tailTest.StartTail("test.txt", Config{MaxBacklogBytes: 8, Logger: DiscardingLogger})
+
+	lines := readAll(tail)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	skipErr, ok := lines[0].Err.(*SkipError)
+	if !ok || skipErr.Bytes != 12 || skipErr.Reason != "max backlog" || lines[0].SeekInfo.Offset != 12 {
+		t.Errorf("unexpected skip line %+v", lines[0])
+	}
+	if lines[1].Text != "again" {
+		t.Errorf("expected again, got %s", lines[1].Text)
+	}
+	if s := tail.Stats(); s.SkippedBytes != 12 {
+		t.Errorf("expected 12 skipped bytes, got %d", s.SkippedBytes)
+	}
+}
+
+func TestIgnoreOlderThan(t *testing.T) {
+	tailTest, cleanup := NewTailTest("ignore-older", t)
+	defer cleanup()
+	tailTest.CreateFile("old.txt", "hello\nworld\n")
+	tailTest.CreateFile("new.txt", "hello\nworld\n")
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(tailTest.path+"/old.txt", old, old); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{IgnoreOlderThan: time.Hour, Logger: DiscardingLogger}
+
+	lines := readAll(tailTest.StartTail("old.txt", config))
+	if len(lines) != 1 {
+		t.Fatalf("expected only the skip line, got %d lines", len(lines))
+	}
+	if skipErr, ok := lines[0].Err.(*SkipError); !ok || skipErr.Bytes != 12 || skipErr.Reason != "ignore older" {
+		t.Errorf("unexpected skip line %+v", lines[0])
+	}
+
+	if lines := readAll(tailTest.StartTail("new.txt", config)); len(lines) != 2 {
+		t.Errorf("expected the 2 lines of the recent file, got %d", len(lines))
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nxadm/tail"
+)
+
+const (
+	colorMatch = "\x1b[01;31m"
+	colorReset = "\x1b[m"
+	separator  = "--"
+)
+
+// filter selects lines like grep: a line is shown when it matches one of the
+// grep patterns (if any) and none of the grepV patterns. Shown lines can be
+// surrounded by before and after lines of context.
+type filter struct {
+	grep   []*regexp.Regexp
+	grepV  []*regexp.Regexp
+	before int
+	after  int
+	color  bool // Highlight the matches of grep with ANSI escapes
+}
+
+func newFilter(grep, grepV []string, ignoreCase bool, before, after int, color bool) (*filter, error) {
+	f := &filter{before: before, after: after, color: color}
+	var err error
+	if f.grep, err = compilePatterns(grep, ignoreCase); err != nil {
+		return nil, err
+	}
+	if f.grepV, err = compilePatterns(grepV, ignoreCase); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func compilePatterns(patterns []string, ignoreCase bool) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// active reports whether the filter selects lines at all.
+func (f *filter) active() bool {
+	return len(f.grep) > 0 || len(f.grepV) > 0
+}
+
+func (f *filter) matches(text string) bool {
+	for _, re := range f.grepV {
+		if re.MatchString(text) {
+			return false
+		}
+	}
+	if len(f.grep) == 0 {
+		return true
+	}
+	for _, re := range f.grep {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight wraps the matches of the grep patterns in color escapes.
+func (f *filter) highlight(text string) string {
+	var spans [][]int
+	for _, re := range f.grep {
+		for _, span := range re.FindAllStringIndex(text, -1) {
+			if span[0] < span[1] {
+				spans = append(spans, span)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(spans); {
+		start, end := spans[i][0], spans[i][1]
+		// merge overlapping matches of different patterns
+		for i++; i < len(spans) && spans[i][0] <= end; i++ {
+			if spans[i][1] > end {
+				end = spans[i][1]
+			}
+		}
+		if start < pos {
+			start = pos
+		}
+		b.WriteString(text[pos:start])
+		b.WriteString(colorMatch + text[start:end] + colorReset)
+		pos = end
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// output is a line selected for output. A nil line stands for the separator
+// between non-adjacent groups of context.
+type output struct {
+	line *tail.Line
+	text string
+}
+
+// fileFilter holds the state of the filter for one file, so that context
+// carries over while the file is followed.
+type fileFilter struct {
+	*filter
+	seq       int          // Number of lines seen
+	pending   []*tail.Line // Previous lines that were not shown
+	afterLeft int          // Lines to show after the last match
+	lastShown int          // seq of the last line shown, 0 if none
+}
+
+func (f *filter) forFile() *fileFilter {
+	return &fileFilter{filter: f}
+}
+
+// process returns what to output for line.
+func (ff *fileFilter) process(line *tail.Line) []output {
+	ff.seq++
+	if !ff.active() {
+		return []output{{line, line.Text}}
+	}
+
+	if !ff.matches(line.Text) {
+		if ff.afterLeft > 0 {
+			ff.afterLeft--
+			ff.lastShown = ff.seq
+			return []output{{line, line.Text}}
+		}
+		if ff.before > 0 {
+			if len(ff.pending) == ff.before {
+				ff.pending = ff.pending[1:]
+			}
+			ff.pending = append(ff.pending, line)
+		}
+		return nil
+	}
+
+	var out []output
+	first := ff.seq - len(ff.pending)
+	if ff.lastShown > 0 && first > ff.lastShown+1 && (ff.before > 0 || ff.after > 0) {
+		out = append(out, output{nil, separator})
+	}
+	for _, l := range ff.pending {
+		out = append(out, output{l, l.Text})
+	}
+	ff.pending = nil
+
+	text := line.Text
+	if ff.color {
+		text = ff.highlight(text)
+	}
+	out = append(out, output{line, text})
+	ff.afterLeft = ff.after
+	ff.lastShown = ff.seq
+	return out
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nxadm/tail"
+)
+
+func run(ff *fileFilter, lines ...string) string {
+	var out []string
+	for _, text := range lines {
+		for _, o := range ff.process(&tail.Line{Text: text}) {
+			out = append(out, o.text)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+func TestFilterContext(t *testing.T) {
+	f, err := newFilter([]string{"err"}, []string{"ignored"}, true, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff := f.forFile()
+
+	got := run(ff, "a", "b", "ERR 1", "c", "d", "e", "err ignored", "f", "err 2")
+	if want := "b,ERR 1,c,--,f,err 2"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	// Context carries over between batches of followed lines.
+	if got, want := run(ff, "g", "h"), "g"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFilterHighlight(t *testing.T) {
+	f, _ := newFilter([]string{"ab", "bc", "x"}, nil, false, 0, 0, true)
+	got := f.highlight("abcd x")
+	if want := colorMatch + "abc" + colorReset + "d " + colorMatch + "x" + colorReset; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// formatter turns an output of filename into the text that is written. It
+// returns false for outputs that are left out, like the separators of context
+// in structured formats.
+type formatter func(filename string, o output) (string, bool)
+
+func newFormatter(format string, embedJSON bool) (formatter, error) {
+	switch format {
+	case "text":
+		return formatText, nil
+	case "json":
+		return func(filename string, o output) (string, bool) {
+			return formatJSON(filename, o, embedJSON)
+		}, nil
+	case "logfmt":
+		return formatLogfmt, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func formatText(filename string, o output) (string, bool) {
+	return o.text, true
+}
+
+// jsonLine is the object written for each line with -format=json. The offset
+// is the one following the line, the event time is set with -timestamp.
+type jsonLine struct {
+	Filename  string                 `json:"filename"`
+	Num       int                    `json:"num"`
+	Offset    int64                  `json:"offset"`
+	Time      string                 `json:"time"`
+	EventTime string                 `json:"event_time,omitempty"`
+	Text      interface{}            `json:"text"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+func formatJSON(filename string, o output, embedJSON bool) (string, bool) {
+	if o.line == nil {
+		return "", false
+	}
+	obj := jsonLine{
+		Filename: filename,
+		Num:      o.line.Num,
+		Offset:   o.line.SeekInfo.Offset,
+		Time:     o.line.Time.Format(time.RFC3339Nano),
+		Text:     o.line.Text,
+		Fields:   o.line.Fields,
+	}
+	if !o.line.EventTime.IsZero() {
+		obj.EventTime = o.line.EventTime.Format(time.RFC3339Nano)
+	}
+	if embedJSON && isJSON(o.line.Text) {
+		obj.Text = json.RawMessage(o.line.Text)
+	}
+	if o.line.Err != nil {
+		obj.Error = o.line.Err.Error()
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// isJSON reports whether text holds a JSON object or array.
+func isJSON(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" || (text[0] != '{' && text[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(text))
+}
+
+func formatLogfmt(filename string, o output) (string, bool) {
+	if o.line == nil {
+		return "", false
+	}
+	pairs := []string{
+		"filename=" + logfmtValue(filename),
+		"num=" + strconv.Itoa(o.line.Num),
+		"offset=" + strconv.FormatInt(o.line.SeekInfo.Offset, 10),
+		"time=" + o.line.Time.Format(time.RFC3339Nano),
+	}
+	if !o.line.EventTime.IsZero() {
+		pairs = append(pairs, "event_time="+o.line.EventTime.Format(time.RFC3339Nano))
+	}
+	pairs = append(pairs, "text="+logfmtValue(o.line.Text))
+	if o.line.Err != nil {
+		pairs = append(pairs, "error="+logfmtValue(o.line.Err.Error()))
+	}
+	// Parsed fields follow, sorted by key and prefixed like their nesting
+	// in JSON, so that they cannot clash with the keys above.
+	keys := make([]string, 0, len(o.line.Fields))
+	for key := range o.line.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pairs = append(pairs, "fields."+key+"="+logfmtValue(fieldString(o.line.Fields[key])))
+	}
+	return strings.Join(pairs, " "), true
+}
+
+// fieldString formats the value of a parsed field for logfmt.
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case int, int64, float64, bool:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// logfmtValue quotes s when it is empty or holds spaces, quotes, equal signs
+// or control characters.
+func logfmtValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+func TestFormats(t *testing.T) {
+	line := &tail.Line{
+		Text:     `{"level":"info","msg":"a <b>"}`,
+		Num:      3,
+		SeekInfo: tail.SeekInfo{Offset: 42},
+		Time:     time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		format string
+		embed  bool
+		want   string
+	}{
+		{"json", false, `{"filename":"app.log","num":3,"offset":42,"time":"2026-10-17T09:30:00Z","text":"{\"level\":\"info\",\"msg\":\"a <b>\"}"}`},
+		{"json", true, `{"filename":"app.log","num":3,"offset":42,"time":"2026-10-17T09:30:00Z","text":{"level":"info","msg":"a <b>"}}`},
+		{"logfmt", false, `filename=app.log num=3 offset=42 time=2026-10-17T09:30:00Z text="{\"level\":\"info\",\"msg\":\"a <b>\"}"`},
+	}
+	for _, test := range tests {
+		format, err := newFormatter(test.format, test.embed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := format("app.log", output{line, line.Text}); got != test.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.format, test.want, got)
+		}
+		if _, ok := format("app.log", output{nil, separator}); ok {
+			t.Errorf("%s: separators should be left out", test.format)
+		}
+	}
+
+	line = &tail.Line{Text: "[not json", Err: errors.New("cooloff")}
+	format, _ := newFormatter("json", true)
+	if got, _ := format("app.log", output{line, line.Text}); got != `{"filename":"app.log","num":0,"offset":0,"time":"0001-01-01T00:00:00Z","text":"[not json","error":"cooloff"}` {
+		t.Errorf("unexpected %s", got)
+	}
+	line = &tail.Line{Text: "x", Fields: map[string]interface{}{"status": 200, "path": "/a b", "sd": map[string]string{"k": "v"}}}
+	format, _ = newFormatter("logfmt", false)
+	if got, _ := format("app.log", output{line, line.Text}); got != `filename=app.log num=0 offset=0 time=0001-01-01T00:00:00Z text=x fields.path="/a b" fields.sd="{\"k\":\"v\"}" fields.status=200` {
+		t.Errorf("unexpected %s", got)
+	}
+	line = &tail.Line{Text: "x", EventTime: time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)}
+	if got, _ := format("app.log", output{line, line.Text}); got != `filename=app.log num=0 offset=0 time=0001-01-01T00:00:00Z event_time=2026-10-17T07:00:00Z text=x` {
+		t.Errorf("unexpected %s", got)
+	}
+	if _, err := newFormatter("xml", false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
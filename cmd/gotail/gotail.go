@@ -9,60 +9,501 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/nxadm/tail"
+	"github.com/nxadm/tail/parse"
+	"github.com/nxadm/tail/sink"
 )
 
-func args2config() (tail.Config, int64) {
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// options holds the settings that are not part of tail.Config.
+type options struct {
+	lines      string
+	bytes      string
+	quiet      bool
+	verbose    bool
+	retry      bool
+	sleep      float64
+	format     string
+	parse      string
+	grok       string
+	grokFiles  stringList
+	timestamps stringList
+	since      timeFlag
+	until      timeFlag
+	merge      bool
+	reverse    bool
+	metrics    string
+	metricsRes stringList
+	lateness   time.Duration
+	maxOpen    int
+	embedJSON  bool
+	outputs    stringList
+	grep       stringList
+	grepV      stringList
+	ignoreCase bool
+	after      int
+	before     int
+	context    int
+	color      string
+}
+
+func args2config() (tail.Config, position, *options) {
 	config := tail.Config{Follow: true}
-	n := int64(0)
 	maxlinesize := int(0)
-	flag.Int64Var(&n, "n", 0, "tail from the last Nth location")
+	opts := &options{}
+	flag.StringVar(&opts.lines, "n", "10", "output the last N lines, or use +N to output starting with line N")
+	flag.StringVar(&opts.bytes, "c", "", "output the last N bytes, or use +N to output starting with byte N")
 	flag.IntVar(&maxlinesize, "max", 0, "max line size")
 	flag.BoolVar(&config.Follow, "f", false, "wait for additional data to be appended to the file")
-	flag.BoolVar(&config.ReOpen, "F", false, "follow, and track file rename/rotation")
+	flag.BoolVar(&config.ReOpen, "F", false, "follow, and track file rename/rotation (implies -retry)")
 	flag.BoolVar(&config.Poll, "p", false, "use polling, instead of inotify")
+	for _, name := range []string{"q", "quiet", "silent"} {
+		flag.BoolVar(&opts.quiet, name, false, "never output headers giving file names")
+	}
+	for _, name := range []string{"v", "verbose"} {
+		flag.BoolVar(&opts.verbose, name, false, "always output headers giving file names")
+	}
+	flag.Var(&opts.since, "since", "output starting with the first line logged at or after this time, e.g. "+
+		"2026-10-17T09:00Z, \"2026-10-17 09:00\" (local time) or 1h30m (ago), instead of the last lines")
+	flag.Var(&opts.until, "until", "stop at the first line logged after this time")
+	flag.BoolVar(&opts.merge, "merge", false, "merge the lines of the files in the order of their timestamps (see -timestamp)")
+	flag.DurationVar(&opts.lateness, "lateness", 2*time.Second, "with -merge, how long lines wait for the lines of other files logged before them")
+	flag.BoolVar(&opts.reverse, "reverse", false, "output the lines in reverse order, newest first; "+
+		"-n and -c tell how many lines or bytes to output (default all)")
+	flag.BoolVar(&opts.retry, "retry", false, "keep trying to open a file if it is inaccessible")
+	flag.Float64Var(&opts.sleep, "s", 0, "with -f, sleep for about N seconds between iterations (default 1, 0.25 with -p)")
+	flag.IntVar(&config.MaxUnchangedStats, "max-unchanged-stats", 5,
+		"with -F, check whether the file was renamed or replaced after N iterations without changes")
+	flag.DurationVar(&config.CloseInactive, "close-inactive", 0, "with -f, close files unchanged for this long until they are modified")
+	flag.IntVar(&opts.maxOpen, "max-open-files", 0, "with -f and -close-inactive, keep at most N files open")
+	flag.DurationVar(&config.IgnoreOlderThan, "ignore-older", 0, "skip to the end of files last modified longer ago than this")
+	flag.Int64Var(&config.MaxBacklogBytes, "max-backlog", 0, "skip to the first line within N bytes of the end of files with more to read")
+	flag.StringVar(&opts.format, "format", "text", "output format: text, or json or logfmt with the filename, line number, offset and read time")
+	flag.StringVar(&opts.parse, "parse", "", "with -format=json or logfmt, add the fields parsed from lines in this format: "+
+		strings.Join(parse.Names(), ", "))
+	flag.StringVar(&opts.grok, "grok", "", "with -format=json or logfmt, add the fields captured by this grok pattern, e.g. %{IP:client}")
+	flag.Var(&opts.grokFiles, "grok-patterns", "read grok pattern definitions from this file (repeatable)")
+	flag.Var(&opts.timestamps, "timestamp", "find the time of events in lines in this layout, for -since, -until and the "+
+		"json and logfmt formats (repeatable): "+strings.Join(tail.DefaultTimestampLayouts, ", ")+", epoch, epoch_ms or a Go time layout")
+	flag.BoolVar(&opts.embedJSON, "embed-json", false, "with -format=json, embed lines holding JSON as objects instead of strings")
+	flag.Var(&opts.grep, "grep", "only show lines matching this regular expression (repeatable)")
+	flag.Var(&opts.grepV, "grep-v", "do not show lines matching this regular expression (repeatable)")
+	flag.BoolVar(&opts.ignoreCase, "i", false, "ignore case in -grep and -grep-v patterns")
+	flag.IntVar(&opts.after, "A", 0, "show N lines of context after matching lines")
+	flag.IntVar(&opts.before, "B", 0, "show N lines of context before matching lines")
+	flag.IntVar(&opts.context, "C", 0, "show N lines of context around matching lines")
+	flag.StringVar(&opts.color, "color", "auto", "highlight matches: auto (when writing to a terminal), always or never")
+	flag.StringVar(&opts.metrics, "metrics-addr", "", "serve Prometheus metrics of the files at /metrics on this address, e.g. :9100")
+	flag.Var(&opts.metricsRes, "metrics-pattern", "with -metrics-addr, count the lines matching a regular expression, "+
+		"given as name=regexp (repeatable)")
+	flag.Var(&opts.outputs, "output", "write lines to this destination (repeatable): -, a file path or a URL with scheme "+
+		strings.Join(sink.Schemes(), ", "))
 	flag.Parse()
 	if config.ReOpen {
 		config.Follow = true
+		opts.retry = true
 	}
+	// Without -retry, missing files are reported right away.
+	config.MustExist = !(opts.retry && config.Follow)
 	config.MaxLineSize = maxlinesize
-	return config, n
+	config.PollInterval = time.Duration(opts.sleep * float64(time.Second))
+
+	if parser, err := opts.parser(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	} else if parser != nil {
+		config.Parser = parser
+	}
+
+	// -since, -until and -merge look for timestamps in the default layouts
+	// unless -timestamp tells otherwise.
+	if len(opts.timestamps) > 0 || !opts.since.IsZero() || !opts.until.IsZero() || opts.merge {
+		extractor, err := tail.NewTimestampExtractor(opts.timestamps...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		config.TimestampExtractor = extractor
+		// Lines without timestamp, like stack traces, belong to the event above.
+		config.TimestampFallback = tail.FallbackPrevious
+	}
+
+	if opts.maxOpen > 0 {
+		if config.CloseInactive <= 0 {
+			fmt.Fprintln(os.Stderr, "-max-open-files requires -close-inactive")
+			os.Exit(1)
+		}
+		config.FileBudget = tail.NewFileBudget(opts.maxOpen)
+	}
+
+	if opts.metrics != "" {
+		watcher := "inotify"
+		if config.Poll {
+			watcher = "polling"
+		}
+		var err error
+		if metrics, err = newRegistry(watcher, opts.metricsRes); err == nil {
+			err = metrics.serve(opts.metrics)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if len(opts.metricsRes) > 0 {
+		fmt.Fprintln(os.Stderr, "-metrics-pattern requires -metrics-addr")
+		os.Exit(1)
+	}
+
+	start, err := parseCount(opts.lines, false)
+	if opts.bytes != "" {
+		start, err = parseCount(opts.bytes, true)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if opts.reverse {
+		if config.Follow || opts.merge {
+			fmt.Fprintln(os.Stderr, "-reverse cannot be used with -f, -F or -merge")
+			os.Exit(1)
+		}
+		// Like tail -r, the whole file is output unless told otherwise.
+		all := true
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "n" || f.Name == "c" {
+				all = false
+			}
+		})
+		if all {
+			start = count{n: 1, fromStart: true}
+		}
+	}
+
+	if len(opts.outputs) == 0 {
+		opts.outputs = stringList{"-"}
+	}
+	if opts.after == 0 {
+		opts.after = opts.context
+	}
+	if opts.before == 0 {
+		opts.before = opts.context
+	}
+	if !opts.since.IsZero() {
+		return config, since{opts.since.Time, config.TimestampExtractor}, opts
+	}
+	return config, start, opts
+}
+
+// parser returns the parser selected by -parse or -grok, if any.
+func (opts *options) parser() (tail.Parser, error) {
+	switch {
+	case opts.parse == "" && opts.grok == "":
+		return nil, nil
+	case opts.parse != "" && opts.grok != "":
+		return nil, fmt.Errorf("-parse and -grok cannot be used together")
+	case opts.format == "text":
+		return nil, fmt.Errorf("-parse and -grok require -format=json or -format=logfmt")
+	case opts.parse != "":
+		return parse.ByName(opts.parse)
+	}
+
+	g := parse.NewGrok()
+	for _, filename := range opts.grokFiles {
+		if err := g.AddPatternsFromFile(filename); err != nil {
+			return nil, err
+		}
+	}
+	return g.Compile(opts.grok)
+}
+
+// toStdout reports whether the only output is the standard output.
+func (opts *options) toStdout() bool {
+	return len(opts.outputs) == 1 && (opts.outputs[0] == "-" || opts.outputs[0] == "stdout")
+}
+
+// useColor reports whether matches should be highlighted.
+func (opts *options) useColor() bool {
+	if opts.format != "text" {
+		return false
+	}
+	switch opts.color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if !opts.toStdout() {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// useHeaders reports whether the output of each file gets a header. Like
+// tail, headers are shown by default when there are several files, but only
+// when they are printed. Structured formats hold the filename instead.
+func (opts *options) useHeaders(files int) bool {
+	if opts.format != "text" {
+		return false
+	}
+	if opts.verbose {
+		return true
+	}
+	return !opts.quiet && files > 1 && opts.toStdout()
 }
 
 func main() {
-	config, n := args2config()
-	if flag.NFlag() < 1 {
-		fmt.Println("need one or more files as arguments")
+	config, start, opts := args2config()
+	if flag.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "need one or more files as arguments")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	format, err := newFormatter(opts.format, opts.embedJSON)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	filter, err := newFilter(opts.grep, opts.grepV, opts.ignoreCase, opts.before, opts.after, opts.useColor())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	if n != 0 {
-		config.Location = &tail.SeekInfo{Offset: -n, Whence: io.SeekEnd}
+	out, err := openOutputs(opts.outputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	p := &printer{out: out, format: format, headers: opts.useHeaders(flag.NArg())}
+
+	if opts.merge {
+		// Headers would be repeated for most lines, the filename prefixes
+		// them instead.
+		p.prefix, p.headers = p.headers, false
+		ok := mergeFiles(flag.Args(), config, start, opts, filter, p)
+		out.Close()
+		if !ok {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	done := make(chan bool)
-	for _, filename := range flag.Args() {
-		go tailFile(filename, config, done)
+	if opts.reverse {
+		status := 0
+		for _, filename := range flag.Args() {
+			if !reverseFile(filename, config, start, opts.until.Time, filter.forFile(), p) {
+				status = 1
+			}
+		}
+		out.Close()
+		os.Exit(status)
 	}
 
-	for range flag.Args() {
-		<-done
+	status := 0
+	if config.Follow {
+		if !followFiles(flag.Args(), config, start, opts.until.Time, filter, p) {
+			status = 1
+		}
+	} else {
+		// Without following, files are output one after another.
+		for _, filename := range flag.Args() {
+			if _, _, ok := tailFile(filename, config, start, opts.until.Time, filter.forFile(), p); !ok {
+				status = 1
+			}
+		}
 	}
+	out.Close()
+	os.Exit(status)
 }
 
-func tailFile(filename string, config tail.Config, done chan bool) {
-	defer func() { done <- true }()
-	t, err := tail.TailFile(filename, config)
-	if err != nil {
-		fmt.Println(err)
-		return
+// tailFile outputs filename from start, until a line logged after until if
+// it is set. It returns the offset following the output, or -1 if it stopped
+// at until, the number of the last line and whether it succeeded.
+func tailFile(filename string, config tail.Config, start position, until time.Time, filter *fileFilter, p *printer) (int64, int, bool) {
+	t := openTail(filename, config, start)
+	if t == nil {
+		return 0, 0, false
+	}
+	if err := p.start(filename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	end, num := int64(0), 0
+	if config.Location != nil {
+		end = config.Location.Offset
 	}
 	for line := range t.Lines {
-		fmt.Println(line.Text)
+		if !until.IsZero() && line.EventTime.After(until) {
+			t.Stop()
+			end = -1
+			break
+		}
+		end, num = line.SeekInfo.Offset, line.Num
+		metrics.observe(filename, line)
+		if err := p.write(filename, filter.process(line)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err := t.Wait(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return end, num, false
 	}
-	err = t.Wait()
+	return end, num, true
+}
+
+// lineNumbers continues the numbers of the lines output before the tail of a
+// file, until the file is reopened and its numbers start over.
+type lineNumbers struct {
+	offset int // Lines output before
+	last   int // Number of the last line of the tail
+}
+
+func (n *lineNumbers) number(line *tail.Line) {
+	// Only the notices of skipped bytes or of the rate limit repeat the
+	// number of the last line, another line with it is of the reopened file.
+	if line.Num < n.last || (line.Num == n.last && line.Err == nil) {
+		n.offset = 0
+	}
+	n.last = line.Num
+	line.Num += n.offset
+}
+
+// followFiles outputs the files from start, then follows them, each until a
+// line logged after until if it is set. It reports whether it succeeded.
+func followFiles(filenames []string, config tail.Config, start position, until time.Time, filter *filter, p *printer) bool {
+	ok := true
+	m := tail.NewManager(config)
+	filters := map[*tail.Tail]*fileFilter{}
+	numbers := map[*tail.Tail]*lineNumbers{}
+	for _, filename := range filenames {
+		ff := filter.forFile()
+		from := start
+		num := 0
+		if f, err := tail.OpenFile(filename); err == nil {
+			f.Close()
+			// Like tail, the files are output one after another first, the
+			// Manager gives them turns afterwards.
+			c := config
+			c.Follow, c.ReOpen, c.MustExist = false, false, true
+			end, last, done := tailFile(filename, c, start, until, ff, p)
+			if !done {
+				ok = false
+				continue
+			}
+			if end < 0 {
+				continue
+			}
+			from, num = resume(end), last
+		}
+
+		t := openTail(filename, config, from)
+		if t == nil {
+			ok = false
+			continue
+		}
+		filters[t] = ff
+		numbers[t] = &lineNumbers{offset: num}
+		m.AddTail(t)
+	}
+	m.Close()
+
+	for line := range m.Lines {
+		if !until.IsZero() && line.EventTime.After(until) {
+			m.Remove(line.Tail.Filename)
+			continue
+		}
+		numbers[line.Tail].number(line.Line)
+		metrics.observe(line.Tail.Filename, line.Line)
+		if err := p.write(line.Tail.Filename, filters[line.Tail].process(line.Line)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err := m.Wait(); err != nil {
+		if errs, isManagerError := err.(tail.ManagerError); isManagerError {
+			for _, err := range errs {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return false
+	}
+	return ok
+}
+
+// mergeFiles outputs the lines of the files from start merged in the order of
+// their timestamps, and reports whether it succeeded.
+func mergeFiles(filenames []string, config tail.Config, start position, opts *options, filter *filter, p *printer) bool {
+	ok := true
+	var tails []*tail.Tail
+	filters := map[*tail.Tail]*fileFilter{}
+	for _, filename := range filenames {
+		t := openTail(filename, config, start)
+		if t == nil {
+			ok = false
+			continue
+		}
+		tails = append(tails, t)
+		filters[t] = filter.forFile()
+	}
+
+	m := tail.NewMerger(opts.lateness, tails...)
+	for line := range m.Lines {
+		if !opts.until.IsZero() && line.EventTime.After(opts.until.Time) {
+			m.Stop()
+			break
+		}
+		metrics.observe(line.Tail.Filename, line.Line)
+		if err := p.write(line.Tail.Filename, filters[line.Tail].process(line.Line)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err := m.Wait(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return ok
+}
+
+// openTail starts tailing filename from start. Errors are reported on the
+// standard error, and nil is returned.
+func openTail(filename string, config tail.Config, start position) *tail.Tail {
+	if f, err := tail.OpenFile(filename); err == nil {
+		var offset int64
+		fi, err := f.Stat()
+		if err == nil {
+			offset, err = start.offset(f, fi.Size())
+		}
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gotail: cannot read %s: %s\n", filename, err)
+			return nil
+		}
+		config.Location = &tail.SeekInfo{Offset: offset, Whence: io.SeekStart}
+	}
+	// Otherwise, a file appearing later is output from its beginning.
+
+	t, err := tail.TailFile(filename, config)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintf(os.Stderr, "gotail: cannot open %s: %s\n", filename, err)
+		return nil
 	}
+	metrics.register(filename, t)
+	return t
 }
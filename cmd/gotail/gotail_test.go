@@ -0,0 +1,42 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nxadm/tail"
+)
+
+func TestLineNumbers(t *testing.T) {
+	// 3 lines were output before the file was followed, then it was reopened.
+	n := &lineNumbers{offset: 3}
+	var nums []int
+	for _, num := range []int{1, 2, 1, 2} {
+		line := &tail.Line{Num: num}
+		n.number(line)
+		nums = append(nums, line.Num)
+	}
+	if nums[0] != 4 || nums[1] != 5 || nums[2] != 1 || nums[3] != 2 {
+		t.Errorf("unexpected line numbers %v", nums)
+	}
+}
+
+func TestLineNumbersOneLine(t *testing.T) {
+	// A one-line file was output, then truncated and rewritten while followed.
+	n := &lineNumbers{offset: 1}
+	var nums []int
+	for _, line := range []*tail.Line{
+		{Num: 1},
+		{Num: 1, Err: errors.New("skipped")},
+		{Num: 1},
+		{Num: 2},
+	} {
+		n.number(line)
+		nums = append(nums, line.Num)
+	}
+	if nums[0] != 2 || nums[1] != 2 || nums[2] != 1 || nums[3] != 2 {
+		t.Errorf("unexpected line numbers %v", nums)
+	}
+}
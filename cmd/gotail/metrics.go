@@ -0,0 +1,183 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nxadm/tail"
+)
+
+// metrics is set with -metrics-addr.
+var metrics *registry
+
+// registry holds the metrics of the tailed files, served in the Prometheus
+// text format.
+type registry struct {
+	watcher  string
+	patterns []namedPattern
+
+	mu    sync.Mutex
+	files map[string]*fileMetrics
+}
+
+type namedPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+type fileMetrics struct {
+	tail    *tail.Tail
+	base    tail.Stats // Counters of the previous tails of the file
+	matches []int64    // By pattern
+}
+
+// newRegistry returns a registry counting the lines matching the patterns,
+// given as name=regexp.
+func newRegistry(watcher string, patterns []string) (*registry, error) {
+	r := &registry{watcher: watcher, files: map[string]*fileMetrics{}}
+	for _, p := range patterns {
+		i := strings.IndexByte(p, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid metrics pattern %q, expected name=regexp", p)
+		}
+		re, err := regexp.Compile(p[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics pattern %q: %s", p, err)
+		}
+		r.patterns = append(r.patterns, namedPattern{p[:i], re})
+	}
+	return r, nil
+}
+
+// serve serves /metrics on addr in the background.
+func (r *registry) serve(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	go http.Serve(l, mux)
+	return nil
+}
+
+func (r *registry) file(filename string) *fileMetrics {
+	m, ok := r.files[filename]
+	if !ok {
+		m = &fileMetrics{matches: make([]int64, len(r.patterns))}
+		r.files[filename] = m
+	}
+	return m
+}
+
+// register sets the tail reading filename. The counters of the tail it
+// replaces are kept.
+func (r *registry) register(filename string, t *tail.Tail) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.file(filename)
+	if m.tail != nil {
+		s := m.tail.Stats()
+		m.base.Lines += s.Lines
+		m.base.Bytes += s.Bytes
+		m.base.Rotations += s.Rotations
+		m.base.Truncations += s.Truncations
+	}
+	m.tail = t
+}
+
+// observe counts the patterns matched by a line of filename.
+func (r *registry) observe(filename string, line *tail.Line) {
+	if r == nil || len(r.patterns) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.file(filename)
+	for i, p := range r.patterns {
+		if p.re.MatchString(line.Text) {
+			m.matches[i]++
+		}
+	}
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.write(w)
+}
+
+// write writes the metrics in the Prometheus text format.
+func (r *registry) write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	filenames := make([]string, 0, len(r.files))
+	for filename := range r.files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	stats := make([]tail.Stats, len(filenames))
+	for i, filename := range filenames {
+		m := r.files[filename]
+		if m.tail != nil {
+			stats[i] = m.tail.Stats()
+		}
+		stats[i].Lines += m.base.Lines
+		stats[i].Bytes += m.base.Bytes
+		stats[i].Rotations += m.base.Rotations
+		stats[i].Truncations += m.base.Truncations
+	}
+
+	counters := []struct {
+		name, typ, help string
+		value           func(s tail.Stats) int64
+	}{
+		{"gotail_lines_total", "counter", "Lines read.", func(s tail.Stats) int64 { return s.Lines }},
+		{"gotail_bytes_total", "counter", "Bytes read.", func(s tail.Stats) int64 { return s.Bytes }},
+		{"gotail_rotations_total", "counter", "Times the file was reopened after being moved, deleted or replaced.",
+			func(s tail.Stats) int64 { return s.Rotations }},
+		{"gotail_truncations_total", "counter", "Times the file was reopened after being truncated.",
+			func(s tail.Stats) int64 { return s.Truncations }},
+		{"gotail_lag_bytes", "gauge", "Bytes of the file left to read.", func(s tail.Stats) int64 { return s.Lag }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.typ)
+		for i, filename := range filenames {
+			fmt.Fprintf(w, "%s{file=%s} %d\n", c.name, labelValue(filename), c.value(stats[i]))
+		}
+	}
+
+	if len(r.patterns) > 0 {
+		fmt.Fprintf(w, "# HELP gotail_pattern_matches_total Lines matching the patterns of -metrics-pattern.\n")
+		fmt.Fprintf(w, "# TYPE gotail_pattern_matches_total counter\n")
+		for _, filename := range filenames {
+			for i, p := range r.patterns {
+				fmt.Fprintf(w, "gotail_pattern_matches_total{file=%s,pattern=%s} %d\n",
+					labelValue(filename), labelValue(p.name), r.files[filename].matches[i])
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP gotail_watcher_info The way changes of the file are watched.\n")
+	fmt.Fprintf(w, "# TYPE gotail_watcher_info gauge\n")
+	for _, filename := range filenames {
+		fmt.Fprintf(w, "gotail_watcher_info{file=%s,watcher=%s} 1\n", labelValue(filename), labelValue(r.watcher))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes a label value for the Prometheus text format.
+func labelValue(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
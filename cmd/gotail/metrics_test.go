@@ -0,0 +1,39 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nxadm/tail"
+)
+
+func TestMetrics(t *testing.T) {
+	r, err := newRegistry("polling", []string{"errors=ERROR|FATAL", "slow=took [0-9]{4,}ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"ERROR boom", "INFO took 1200ms", "FATAL took 12000ms", "INFO ok"} {
+		r.observe(`app "1".log`, &tail.Line{Text: text})
+	}
+
+	var buf bytes.Buffer
+	r.write(&buf)
+	for _, want := range []string{
+		"# TYPE gotail_lines_total counter\n",
+		`gotail_lines_total{file="app \"1\".log"} 0` + "\n",
+		`gotail_pattern_matches_total{file="app \"1\".log",pattern="errors"} 2` + "\n",
+		`gotail_pattern_matches_total{file="app \"1\".log",pattern="slow"} 2` + "\n",
+		`gotail_watcher_info{file="app \"1\".log",watcher="polling"} 1` + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in\n%s", want, buf.String())
+		}
+	}
+
+	if _, err := newRegistry("inotify", []string{"ERROR"}); err == nil {
+		t.Error("expected an error for a pattern without name")
+	}
+}
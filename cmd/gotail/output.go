@@ -0,0 +1,87 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nxadm/tail/sink"
+)
+
+func openOutputs(specs []string) (sink.Sink, error) {
+	var sinks []sink.Sink
+	for _, spec := range specs {
+		s, err := sink.Open(spec)
+		if err != nil {
+			sink.Multi(sinks...).Close()
+			return nil, fmt.Errorf("cannot open output %s: %s", spec, err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sink.Multi(sinks...), nil
+}
+
+// printer serializes the output of the tailed files. With headers, the
+// output of each file is preceded by a "==> filename <==" header, printed
+// again whenever the output switches from one file to another. With prefix,
+// each line of text is preceded by its filename instead.
+type printer struct {
+	out     sink.Sink
+	format  formatter
+	headers bool
+	prefix  bool
+
+	mu   sync.Mutex
+	last string // Filename of the last output
+}
+
+// start begins the output of filename, with its header even if it has no
+// lines, like tail.
+func (p *printer) start(filename string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.header(filename)
+}
+
+func (p *printer) write(filename string, outputs []output) error {
+	if len(outputs) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.header(filename); err != nil {
+		return err
+	}
+	for _, o := range outputs {
+		text, ok := p.format(filename, o)
+		if !ok {
+			continue
+		}
+		if p.prefix && o.line != nil {
+			text = filename + ": " + text
+		}
+		if err := p.out.Write(&sink.Record{Filename: filename, Line: o.line, Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header writes the header of filename if the last output was of another
+// file. p.mu must be held.
+func (p *printer) header(filename string) error {
+	if p.headers && filename != p.last {
+		if p.last != "" {
+			if err := p.out.Write(&sink.Record{Filename: filename}); err != nil {
+				return err
+			}
+		}
+		if err := p.out.Write(&sink.Record{Filename: filename, Text: "==> " + filename + " <=="}); err != nil {
+			return err
+		}
+	}
+	p.last = filename
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nxadm/tail"
+	"github.com/nxadm/tail/sink"
+)
+
+func TestPrinterHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	p := &printer{out: sink.NewWriterSink(&buf), format: formatText, headers: true}
+	write := func(filename string, texts ...string) {
+		var outputs []output
+		for _, text := range texts {
+			outputs = append(outputs, output{&tail.Line{Text: text}, text})
+		}
+		p.write(filename, outputs)
+	}
+
+	write("a", "1", "2")
+	write("a", "3")
+	write("b")
+	write("b", "4")
+	write("a", "5")
+	p.start("empty")
+	p.start("c")
+	write("c", "6")
+
+	want := "==> a <==\n1\n2\n3\n\n==> b <==\n4\n\n==> a <==\n5\n\n==> empty <==\n\n==> c <==\n6\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrinterPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	p := &printer{out: sink.NewWriterSink(&buf), format: formatText, prefix: true}
+	p.write("a.log", []output{{&tail.Line{Text: "1"}, "1"}, {nil, separator}})
+	p.write("b.log", []output{{&tail.Line{Text: "2"}, "2"}})
+
+	want := "a.log: 1\n--\nb.log: 2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
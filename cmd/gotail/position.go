@@ -0,0 +1,210 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nxadm/tail"
+	"github.com/nxadm/tail/sink"
+)
+
+// position tells where the output of a file starts.
+type position interface {
+	// offset returns the offset in f at which the output starts, when the
+	// first size bytes of f are output.
+	offset(f *os.File, size int64) (int64, error)
+}
+
+// count is the argument of -n or -c: the number of lines or bytes to output
+// from the end of the file or, with a leading "+", the line or byte to start
+// from.
+type count struct {
+	n         int64
+	fromStart bool
+	bytes     bool
+}
+
+func parseCount(s string, bytes bool) (count, error) {
+	c := count{bytes: bytes}
+	switch {
+	case strings.HasPrefix(s, "+"):
+		c.fromStart = true
+		s = s[1:]
+	case strings.HasPrefix(s, "-"):
+		s = s[1:]
+	}
+	var err error
+	if bytes {
+		c.n, err = sink.ParseSize(s)
+	} else {
+		c.n, err = strconv.ParseInt(s, 10, 64)
+		if err == nil && c.n < 0 {
+			err = fmt.Errorf("negative count")
+		}
+	}
+	if err != nil {
+		unit := "lines"
+		if bytes {
+			unit = "bytes"
+		}
+		return c, fmt.Errorf("invalid number of %s: %q", unit, s)
+	}
+	return c, nil
+}
+
+func (c count) offset(f *os.File, size int64) (int64, error) {
+	switch {
+	case c.bytes && c.fromStart:
+		if c.n <= 1 {
+			return 0, nil
+		}
+		return min64(c.n-1, size), nil
+	case c.bytes:
+		return size - min64(c.n, size), nil
+	case c.fromStart:
+		return lineOffset(f, c.n-1)
+	default:
+		return lastLinesOffset(f, size, c.n)
+	}
+}
+
+// lineOffset returns the offset of the line following the first n lines of f.
+func lineOffset(f *os.File, n int64) (int64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(f)
+	offset := int64(0)
+	for ; n > 0; n-- {
+		line, err := r.ReadSlice('\n')
+		offset += int64(len(line))
+		if err == bufio.ErrBufferFull {
+			n++
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+// lastLinesOffset returns the offset of the start of the last n lines of f,
+// which is size bytes long. An unterminated last line counts as a line.
+func lastLinesOffset(f *os.File, size, n int64) (int64, error) {
+	r := tail.NewReverseReader(f, size)
+	for ; n > 0; n-- {
+		if _, _, err := r.ReadLine(); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return r.Offset(), nil
+}
+
+// countLines returns the number of lines in the first size bytes of f. An
+// unterminated last line counts as a line.
+func countLines(f *os.File, size int64) (int, error) {
+	r := io.NewSectionReader(f, 0, size)
+	buf := make([]byte, 32*1024)
+	n := 0
+	last := byte('\n')
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			n += bytes.Count(buf[:m], []byte{'\n'})
+			last = buf[m-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		n++
+	}
+	return n, nil
+}
+
+// resume is the position at which the output of a file resumes.
+type resume int64
+
+func (r resume) offset(f *os.File, size int64) (int64, error) {
+	return int64(r), nil
+}
+
+// since is the position given by -since: the first line logged at or after
+// time.
+type since struct {
+	time      time.Time
+	extractor tail.TimestampExtractor
+}
+
+func (s since) offset(f *os.File, size int64) (int64, error) {
+	return tail.TimeOffset(f, size, s.time, s.extractor)
+}
+
+// timeFlag is the argument of -since or -until: a date and time, in the local
+// time zone unless one is given, or a duration before now like 1h30m.
+type timeFlag struct {
+	time.Time
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (f *timeFlag) String() string {
+	if f.IsZero() {
+		return ""
+	}
+	return f.Format(time.RFC3339Nano)
+}
+
+func (f *timeFlag) Set(s string) error {
+	t, err := parseTime(s, time.Now())
+	if err != nil {
+		return err
+	}
+	f.Time = t
+	return nil
+}
+
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 2026-10-17T09:00Z, \"2026-10-17 09:00\" or 1h30m", s)
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountOffset(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	// Lines long enough to cross the blocks read backwards.
+	long := strings.Repeat("x", 5000)
+	content := "one\n" + long + "\nthree\nfour\n"
+	f.WriteString(content)
+
+	tests := []struct {
+		arg   string
+		bytes bool
+		want  string
+	}{
+		{"10", false, content},
+		{"2", false, "three\nfour\n"},
+		{"-3", false, long + "\nthree\nfour\n"},
+		{"0", false, ""},
+		{"+1", false, content},
+		{"+3", false, "three\nfour\n"},
+		{"+9", false, ""},
+		{"5", true, "four\n"},
+		{"+5", true, content[4:]},
+		{"+0", true, content},
+		{"1k", true, content[len(content)-1024:]},
+	}
+	for _, test := range tests {
+		c, err := parseCount(test.arg, test.bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset, err := c.offset(f, int64(len(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := content[offset:]; got != test.want {
+			t.Errorf("%s (bytes: %v): expected %.20q, got %.20q", test.arg, test.bytes, test.want, got)
+		}
+	}
+
+	for _, arg := range []string{"", "x", "+-1"} {
+		if _, err := parseCount(arg, false); err == nil {
+			t.Errorf("parseCount(%q) should fail", arg)
+		}
+	}
+}
+
+func TestLastLinesUnterminated(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString("one\ntwo\nthree")
+
+	c, _ := parseCount("2", false)
+	if offset, _ := c.offset(f, 13); offset != 4 {
+		t.Errorf("expected offset 4, got %d", offset)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2026-10-17T09:00Z":         time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+		"2026-10-17T09:00:30+02:00": time.Date(2026, 10, 17, 7, 0, 30, 0, time.UTC),
+		"2026-10-17 09:00":          time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local),
+		"2026-10-17":                time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local),
+		"1h30m":                     time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC),
+	}
+	for arg, want := range tests {
+		got, err := parseTime(arg, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s (%v)", arg, want, got, err)
+		}
+	}
+	if _, err := parseTime("yesterday", now); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+// reverseFile outputs the lines of filename from the last one back to start,
+// leaving out the lines logged after until if it is set, and reports whether
+// it succeeded.
+func reverseFile(filename string, config tail.Config, start position, until time.Time, filter *fileFilter, p *printer) bool {
+	f, err := tail.OpenFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotail: cannot open %s: %s\n", filename, err)
+		return false
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotail: cannot read %s: %s\n", filename, err)
+		return false
+	}
+	// The lines to output from the end are counted back from -until.
+	size := fi.Size()
+	if !until.IsZero() {
+		size, err = tail.TimeOffset(f, size, until.Add(time.Nanosecond), config.TimestampExtractor)
+	}
+	var stop int64
+	var num int
+	if err == nil {
+		stop, err = start.offset(f, size)
+	}
+	if err == nil {
+		num, err = countLines(f, size)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotail: cannot read %s: %s\n", filename, err)
+		return false
+	}
+
+	if err := p.start(filename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	r := tail.NewReverseReader(f, size)
+	now := time.Now()
+	for r.Offset() > stop {
+		next := r.Offset()
+		text, offset, err := r.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gotail: cannot read %s: %s\n", filename, err)
+			return false
+		}
+		if offset < stop {
+			// Like forwards, output starts at the byte given by -c.
+			text = text[min64(stop-offset, int64(len(text))):]
+		}
+
+		line := &tail.Line{Text: text, Num: num, SeekInfo: tail.SeekInfo{Offset: next}, Time: now}
+		num--
+		if config.Parser != nil {
+			if line.Fields, err = config.Parser.Parse(text); err != nil {
+				line.Fields, line.Err = nil, &tail.ParseError{Err: err}
+			}
+		}
+		if config.TimestampExtractor != nil {
+			line.EventTime, _ = config.TimestampExtractor.Extract(text, line.Fields)
+		}
+		metrics.observe(filename, line)
+		if err := p.write(filename, filter.process(line)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	return true
+}
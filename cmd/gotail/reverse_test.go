@@ -0,0 +1,72 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nxadm/tail"
+	"github.com/nxadm/tail/sink"
+)
+
+func TestReverseFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("2026-10-17T09:00:00Z one\r\n2026-10-17T09:00:01Z two\n  trace\n2026-10-17T09:00:02Z three")
+	f.Close()
+
+	extractor, _ := tail.NewTimestampExtractor()
+	config := tail.Config{TimestampExtractor: extractor}
+	filter, _ := newFilter(nil, nil, false, 0, 0, false)
+	tests := []struct {
+		start position
+		until time.Time
+		want  string
+	}{
+		{count{n: 1, fromStart: true}, time.Time{}, "2026-10-17T09:00:02Z three\n  trace\n2026-10-17T09:00:01Z two\n2026-10-17T09:00:00Z one\n"},
+		{count{n: 2}, time.Time{}, "2026-10-17T09:00:02Z three\n  trace\n"},
+		{count{n: 1, fromStart: true}, time.Date(2026, 10, 17, 9, 0, 1, 0, time.UTC), "  trace\n2026-10-17T09:00:01Z two\n2026-10-17T09:00:00Z one\n"},
+		{count{n: 2}, time.Date(2026, 10, 17, 9, 0, 1, 0, time.UTC), "  trace\n2026-10-17T09:00:01Z two\n"},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		p := &printer{out: sink.NewWriterSink(&buf), format: formatText}
+		if !reverseFile(f.Name(), config, test.start, test.until, filter.forFile(), p) {
+			t.Fatalf("%d: failed", i)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%d: expected %q, got %q", i, test.want, got)
+		}
+	}
+}
+
+func TestReverseFileNumbers(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("one\ntwo\nthree\nfour")
+	f.Close()
+
+	var buf bytes.Buffer
+	number := func(filename string, o output) (string, bool) {
+		return strconv.Itoa(o.line.Num) + " " + o.text, true
+	}
+	p := &printer{out: sink.NewWriterSink(&buf), format: number}
+	filter, _ := newFilter(nil, nil, false, 0, 0, false)
+	if !reverseFile(f.Name(), tail.Config{}, count{n: 3}, time.Time{}, filter.forFile(), p) {
+		t.Fatal("failed")
+	}
+	if got := buf.String(); got != "4 four\n3 three\n2 two\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
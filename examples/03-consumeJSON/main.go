@@ -1,12 +1,11 @@
 // Consume and unmarshall JSON.
 //
 // In this example JSON lines are added by createJSON in a tight loop.
-// Each line is unmarshalled and a field printed.
+// Each line is decoded by a tail.JSONDecoder and a field printed.
 // Exit with Ctrl+C.
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -28,20 +27,23 @@ func main() {
 	defer file.Close()
 	defer os.Remove(file.Name())
 
-	t, err := tail.TailFile(file.Name(), tail.Config{Follow: true})
+	d, err := tail.TailJSON(file.Name(), tail.Config{Follow: true})
 	if err != nil {
 		panic(err)
 	}
+	// Malformed lines are skipped after being reported here.
+	d.OnError = func(line *tail.Line, err error) {
+		fmt.Printf("Invalid JSON on line %d: %s\n", line.Num, err)
+	}
 
 	go createJSON(file)
-	var js jsonStruct
-	for line := range t.Lines {
-		fmt.Printf("JSON: " + line.Text + "\n")
-
-		err := json.Unmarshal([]byte(line.Text), &js)
+	for {
+		var js jsonStruct
+		line, err := d.Decode(&js)
 		if err != nil {
 			panic(err)
 		}
+		fmt.Printf("JSON: " + line.Text + "\n")
 		fmt.Printf("JSON counter field: " + js.Counter + "\n")
 	}
 }
@@ -0,0 +1,149 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/tomb.v1"
+)
+
+// FileBudget limits the number of files open at once by the tails sharing it
+// through Config.FileBudget, such as the tails of a Manager. A tail that
+// would exceed the budget waits for another one to close its file, so
+// CloseInactive should be set for the idle files to give theirs back.
+type FileBudget struct {
+	slots chan struct{}
+}
+
+// NewFileBudget returns a FileBudget of max open files.
+func NewFileBudget(max int) *FileBudget {
+	return &FileBudget{slots: make(chan struct{}, max)}
+}
+
+// Open returns the number of files open under the budget.
+func (b *FileBudget) Open() int {
+	return len(b.slots)
+}
+
+func (b *FileBudget) acquire(t *tomb.Tomb) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-t.Dying():
+		return tomb.ErrDying
+	}
+}
+
+func (b *FileBudget) release() {
+	<-b.slots
+}
+
+// inactiveFile is the file closed by CloseInactive.
+type inactiveFile struct {
+	info   os.FileInfo
+	offset int64
+}
+
+// openFile opens name within the FileBudget.
+func (tail *Tail) openFile() (*os.File, error) {
+	if tail.FileBudget != nil {
+		if err := tail.FileBudget.acquire(&tail.Tomb); err != nil {
+			return nil, err
+		}
+	}
+	name := tail.name
+	var err error
+	if tail.FollowSymlinks || tail.SymlinkRoot != "" {
+		name, err = tail.resolve()
+	}
+	var f *os.File
+	if err == nil {
+		f, err = OpenFile(name)
+	}
+	if err != nil {
+		if tail.FileBudget != nil {
+			tail.FileBudget.release()
+		}
+		return nil, err
+	}
+	if tail.FollowSymlinks {
+		tail.target = ""
+		if name != filepath.Clean(tail.name) {
+			tail.target = name
+		}
+	}
+	return f, nil
+}
+
+// closeInactive closes the file, remembering it to reopen it when it changes.
+func (tail *Tail) closeInactive() error {
+	offset, err := tail.Tell()
+	if err != nil {
+		return err
+	}
+	fi, err := tail.file.Stat()
+	if err != nil {
+		return err
+	}
+	tail.lk.Lock()
+	tail.file.Close()
+	tail.file = nil
+	tail.inactive = &inactiveFile{info: fi, offset: offset}
+	tail.lk.Unlock()
+	if tail.FileBudget != nil {
+		tail.FileBudget.release()
+	}
+	tail.updateStats(func(s *Stats) { s.Inactive = true })
+	return nil
+}
+
+// wakeUp reopens the file closed by closeInactive, once it was modified.
+// It returns false if name no longer names it: the file must then be
+// handled as replaced.
+func (tail *Tail) wakeUp() (bool, error) {
+	in := tail.inactive
+	f, err := tail.openFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	fi, err := f.Stat()
+	if err != nil || !os.SameFile(fi, in.info) {
+		f.Close()
+		if tail.FileBudget != nil {
+			tail.FileBudget.release()
+		}
+		return false, err
+	}
+
+	offset := in.offset
+	if fi.Size() < offset {
+		// Truncated in the meantime.
+		tail.Logger.Printf("Re-opening truncated file %s ...", tail.name)
+		offset = 0
+		tail.lineNum = 0
+		tail.updateStats(func(s *Stats) { s.Truncations++; s.Reopens++ })
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		if tail.FileBudget != nil {
+			tail.FileBudget.release()
+		}
+		return false, err
+	}
+	tail.lk.Lock()
+	tail.file = f
+	tail.inactive = nil
+	tail.lk.Unlock()
+	tail.updateStats(func(s *Stats) { s.Inactive = false; s.Offset = offset })
+	if offset == 0 && in.offset > 0 {
+		tail.reopened()
+	}
+	tail.openReader()
+	return true, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloseInactive(t *testing.T) {
+	tailTest, cleanup := NewTailTest("close-inactive", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\n")
+	config := Config{Follow: true, CloseInactive: 100 * time.Millisecond, Logger: DiscardingLogger}
+	tail := tailTest.StartTail("test.txt", config)
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	<-tail.Lines
+	waitForStats(t, tail, func(s Stats) bool { return s.Inactive })
+	if offset, _ := tail.Tell(); offset != 6 {
+		t.Errorf("expected the offset of the closed file, got %d", offset)
+	}
+
+	tailTest.AppendFile("test.txt", "world\n")
+	if line := <-tail.Lines; line.Text != "world" || line.Num != 2 {
+		t.Errorf("expected line 2 world, got %d %s", line.Num, line.Text)
+	}
+	waitForStats(t, tail, func(s Stats) bool { return s.Inactive })
+
+	tailTest.TruncateFile("test.txt", "a\n")
+	if line := <-tail.Lines; line.Text != "a" {
+		t.Errorf("expected a, got %s", line.Text)
+	}
+	s := waitForStats(t, tail, func(s Stats) bool { return s.Truncations == 1 })
+	if s.Offset != 2 {
+		t.Errorf("unexpected stats after truncation %+v", s)
+	}
+}
+
+func TestFileBudget(t *testing.T) {
+	tailTest, cleanup := NewTailTest("file-budget", t)
+	defer cleanup()
+	tailTest.CreateFile("a.txt", "a\n")
+	tailTest.CreateFile("b.txt", "b\n")
+	budget := NewFileBudget(1)
+	m := NewManager(Config{
+		Follow:        true,
+		CloseInactive: 100 * time.Millisecond,
+		FileBudget:    budget,
+		Logger:        DiscardingLogger,
+	})
+	defer m.Stop()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := m.Add(tailTest.path + "/" + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second file is read once the first one was closed.
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case line := <-m.Lines:
+			seen[line.Text] = true
+			if n := budget.Open(); n > 1 {
+				t.Errorf("expected at most 1 open file, got %d", n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the lines")
+		}
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("expected the lines of both files, got %v", seen)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LatestOrder tells which file is the newest for TailLatest.
+type LatestOrder interface {
+	// Newest returns the newest of names, or "" if there is none.
+	Newest(names []string) string
+}
+
+// ByModTime orders files by modification time, then by name.
+type ByModTime struct{}
+
+// Newest returns the file of names modified last.
+func (ByModTime) Newest(names []string) string {
+	var newest string
+	var newestTime time.Time
+	for _, name := range names {
+		fi, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if newest == "" || fi.ModTime().After(newestTime) ||
+			(fi.ModTime().Equal(newestTime) && name > newest) {
+			newest, newestTime = name, fi.ModTime()
+		}
+	}
+	return newest
+}
+
+// ByName orders files by name, like app-2024-01-31.log.
+type ByName struct{}
+
+// Newest returns the last of names.
+func (ByName) Newest(names []string) string {
+	var newest string
+	for _, name := range names {
+		if name > newest {
+			newest = name
+		}
+	}
+	return newest
+}
+
+// ByNameTime orders files by the time in their name, parsed with Layout
+// (see time.Parse), which describes the whole base name, like
+// "app-20060102-1504.log". Files with other names are ignored.
+type ByNameTime struct {
+	Layout string
+}
+
+// Newest returns the file of names with the latest time.
+func (o ByNameTime) Newest(names []string) string {
+	var newest string
+	var newestTime time.Time
+	for _, name := range names {
+		t, err := time.Parse(o.Layout, filepath.Base(name))
+		if err != nil {
+			continue
+		}
+		if newest == "" || t.After(newestTime) {
+			newest, newestTime = name, t
+		}
+	}
+	return newest
+}
+
+// latestFiles is the state of TailLatest.
+type latestFiles struct {
+	pattern string
+	order   LatestOrder
+}
+
+// TailLatest begins tailing the newest of the files matching the glob
+// pattern, according to order (ByModTime if nil). With Follow, it switches to
+// a newer file once one appears, after reading the current file to its end,
+// looking for one every PollInterval (1 second if zero). For apps starting a
+// new file instead of renaming it, which ReOpen cannot follow.
+//
+// Filename is the file read first, the File of Tail.Stats the file being
+// read. Checkpoints are saved under pattern.
+func TailLatest(pattern string, order LatestOrder, config Config) (*Tail, error) {
+	if order == nil {
+		order = ByModTime{}
+	}
+	latest := &latestFiles{pattern: pattern, order: order}
+	filename, err := latest.newest()
+	if err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		return nil, fmt.Errorf("Unable to find a file matching %s", pattern)
+	}
+	return tailFile(filename, config, latest)
+}
+
+// newest returns the newest file matching the pattern.
+func (latest *latestFiles) newest() (string, error) {
+	matches, err := filepath.Glob(latest.pattern)
+	if err != nil {
+		return "", fmt.Errorf("Unable to match %s: %s", latest.pattern, err)
+	}
+	files := matches[:0]
+	for _, name := range matches {
+		if fi, err := os.Stat(name); err == nil && !fi.IsDir() {
+			files = append(files, name)
+		}
+	}
+	return latest.order.Newest(files), nil
+}
+
+// checkLatest looks for a file newer than the one being read, to switch to
+// it once the current one is read to its end.
+func (tail *Tail) checkLatest() {
+	name, err := tail.latest.newest()
+	if err != nil {
+		tail.Logger.Printf("%s", err)
+		return
+	}
+	if name != "" && name != tail.name {
+		tail.next = name
+	}
+}
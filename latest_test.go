@@ -0,0 +1,103 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLatestOrder(t *testing.T) {
+	tailTest, cleanup := NewTailTest("latest-order", t)
+	defer cleanup()
+	tailTest.CreateFile("app-20261017-0930.log", "")
+	tailTest.CreateFile("app-20261016-2300.log", "")
+	tailTest.CreateFile("app-9.log", "")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(tailTest.path+"/app-9.log", old, old); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		tailTest.path + "/app-20261016-2300.log",
+		tailTest.path + "/app-9.log",
+		tailTest.path + "/app-20261017-0930.log",
+	}
+
+	if newest := (ByName{}).Newest(names); newest != names[1] {
+		t.Errorf("expected %s by name, got %s", names[1], newest)
+	}
+	if newest := (ByNameTime{Layout: "app-20060102-1504.log"}).Newest(names); newest != names[2] {
+		t.Errorf("expected %s by name time, got %s", names[2], newest)
+	}
+	if newest := (ByModTime{}).Newest(names); newest == names[1] {
+		t.Errorf("expected a file modified last, got %s", newest)
+	}
+}
+
+func TestTailLatest(t *testing.T) {
+	testTailLatest(t, "latest", false)
+}
+
+func TestTailLatestPolling(t *testing.T) {
+	testTailLatest(t, "latest-polling", true)
+}
+
+func testTailLatest(t *testing.T, name string, poll bool) {
+	tailTest, cleanup := NewTailTest(name, t)
+	defer cleanup()
+	tailTest.CreateFile("app-1.log", "a\n")
+	tailTest.CreateFile("other.txt", "z\n")
+	tail, err := TailLatest(tailTest.path+"/app-*.log", ByName{}, Config{
+		Follow:       true,
+		Poll:         poll,
+		PollInterval: 100 * time.Millisecond,
+		Logger:       DiscardingLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	next := func() string {
+		select {
+		case line := <-tail.Lines:
+			return line.Text
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a line")
+			return ""
+		}
+	}
+	if text := next(); text != "a" {
+		t.Fatalf("expected a, got %s", text)
+	}
+
+	// The current file is read to its end before switching.
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("app-1.log", "b\n")
+	tailTest.CreateFile("app-2.log", "c\n")
+	for _, expected := range []string{"b", "c"} {
+		if text := next(); text != expected {
+			t.Fatalf("expected %s, got %s", expected, text)
+		}
+	}
+	s := waitForStats(t, tail, func(s Stats) bool { return s.Switches == 1 })
+	if s.File != tailTest.path+"/app-2.log" {
+		t.Errorf("expected to follow app-2.log, got %s", s.File)
+	}
+
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("app-2.log", "d\n")
+	if text := next(); text != "d" {
+		t.Errorf("expected d, got %s", text)
+	}
+}
+
+func TestTailLatestNoMatch(t *testing.T) {
+	tailTest, cleanup := NewTailTest("latest-no-match", t)
+	defer cleanup()
+	if _, err := TailLatest(tailTest.path+"/app-*.log", nil, Config{}); err == nil {
+		t.Error("expected an error without a matching file")
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/tomb.v1"
+)
+
+// Manager tails many files, and delivers their lines on a single channel.
+// Files take turns: a file with many lines to read waits for the lines of the
+// other files before its next line is delivered, so that it cannot starve
+// them.
+//
+// Setting CloseInactive and a FileBudget in the Config of the Manager caps
+// the number of files open by its tails, when following many mostly idle
+// files.
+//
+// A Manager runs until Stop is called, or until Close was called and its
+// tails have stopped. Wait and Err then return a ManagerError with the errors
+// of the tails, if any.
+type Manager struct {
+	Lines  chan *MergedLine // A consumable channel of *MergedLine
+	Config                  // Configuration of the tails started by Add
+
+	mu     sync.Mutex
+	tails  map[string]*managedTail
+	closed bool
+	errs   ManagerError
+
+	ready chan managedLine
+	ended chan struct{}
+
+	tomb.Tomb // provides: Done, Kill, Dying
+}
+
+type managedTail struct {
+	*Tail
+	next    chan struct{} // The line was delivered, another one can be read
+	removed chan struct{}
+}
+
+type managedLine struct {
+	tail *managedTail
+	line *Line
+}
+
+// ManagerError holds the errors of the tails of a Manager, by filename.
+type ManagerError map[string]error
+
+func (e ManagerError) Error() string {
+	var msgs []string
+	for filename, err := range e {
+		msgs = append(msgs, filename+": "+err.Error())
+	}
+	sort.Strings(msgs)
+	return strings.Join(msgs, "; ")
+}
+
+var errManagerStopped = errors.New("Unable to add a tail to a stopped manager")
+
+// NewManager returns a Manager starting tails with config.
+func NewManager(config Config) *Manager {
+	m := &Manager{
+		Lines:  make(chan *MergedLine),
+		Config: config,
+		tails:  make(map[string]*managedTail),
+		ready:  make(chan managedLine),
+		ended:  make(chan struct{}, 1),
+	}
+	go m.run()
+	return m
+}
+
+// Add starts tailing filename with the configuration of the Manager.
+func (m *Manager) Add(filename string) (*Tail, error) {
+	m.mu.Lock()
+	_, ok := m.tails[filename]
+	m.mu.Unlock()
+	if ok {
+		return nil, fmt.Errorf("Unable to add %s twice", filename)
+	}
+	t, err := TailFile(filename, m.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.AddTail(t); err != nil {
+		t.Stop()
+		return nil, err
+	}
+	return t, nil
+}
+
+// AddTail hands a tail over to the Manager, which delivers its lines and
+// stops it. It is used for tails with their own configuration.
+func (m *Manager) AddTail(t *Tail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errManagerStopped
+	}
+	if _, ok := m.tails[t.Filename]; ok {
+		return fmt.Errorf("Unable to add %s twice", t.Filename)
+	}
+	mt := &managedTail{Tail: t, next: make(chan struct{}, 1), removed: make(chan struct{})}
+	m.tails[t.Filename] = mt
+	go m.receive(mt)
+	return nil
+}
+
+// Remove stops tailing filename. Its lines that were not delivered yet are
+// dropped.
+func (m *Manager) Remove(filename string) error {
+	m.mu.Lock()
+	mt, ok := m.tails[filename]
+	delete(m.tails, filename)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("Unable to remove %s, it is not tailed", filename)
+	}
+	close(mt.removed)
+	mt.Stop()
+	m.signalEnd()
+	return nil
+}
+
+// Tails returns the tails of the Manager.
+func (m *Manager) Tails() []*Tail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tails := make([]*Tail, 0, len(m.tails))
+	for _, mt := range m.tails {
+		tails = append(tails, mt.Tail)
+	}
+	return tails
+}
+
+// Close tells that no more tails will be added: the Manager stops once its
+// tails have.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	m.signalEnd()
+}
+
+// Stop stops the Manager and its tails. It returns the errors of the tails
+// that stopped before.
+func (m *Manager) Stop() error {
+	m.Close()
+	var err error
+	m.mu.Lock()
+	if m.errs != nil {
+		err = m.errs
+	}
+	m.mu.Unlock()
+	m.Kill(err)
+	return m.Wait()
+}
+
+// StopAtEOF stops the tails as soon as the end of their file is reached, then
+// the Manager.
+func (m *Manager) StopAtEOF() error {
+	m.mu.Lock()
+	for _, mt := range m.tails {
+		mt.Kill(errStopAtEOF)
+	}
+	m.mu.Unlock()
+	m.Close()
+	return m.Wait()
+}
+
+// receive hands the lines of a tail over to run, one at a time.
+func (m *Manager) receive(mt *managedTail) {
+	for line := range mt.Lines {
+		select {
+		case m.ready <- managedLine{mt, line}:
+		case <-mt.removed:
+			return
+		case <-m.Dying():
+			return
+		}
+		select {
+		case <-mt.next:
+		case <-mt.removed:
+			return
+		case <-m.Dying():
+			return
+		}
+	}
+
+	err := mt.Wait()
+	m.mu.Lock()
+	select {
+	case <-mt.removed:
+	default:
+		delete(m.tails, mt.Filename)
+		if err != nil && err != errStopAtEOF {
+			if m.errs == nil {
+				m.errs = ManagerError{}
+			}
+			m.errs[mt.Filename] = err
+		}
+	}
+	m.mu.Unlock()
+	m.signalEnd()
+}
+
+// signalEnd tells run that a tail ended, or that the Manager was closed.
+func (m *Manager) signalEnd() {
+	select {
+	case m.ended <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the lines of the tails in the order they were read. As each
+// tail reads its next line once its previous one was delivered, tails with
+// lines to deliver take turns.
+func (m *Manager) run() {
+	defer m.Done()
+	defer close(m.Lines)
+
+	var queue []managedLine
+	for {
+		// Lines of removed tails are dropped.
+		for len(queue) > 0 && isClosed(queue[0].tail.removed) {
+			queue = queue[1:]
+		}
+
+		var lines chan *MergedLine
+		var next *MergedLine
+		if len(queue) > 0 {
+			lines = m.Lines
+			next = &MergedLine{queue[0].line, queue[0].tail.Tail}
+		} else if m.finished() {
+			m.mu.Lock()
+			if m.errs != nil {
+				m.Kill(m.errs)
+			}
+			m.mu.Unlock()
+			return
+		}
+
+		select {
+		case l := <-m.ready:
+			queue = append(queue, l)
+		case lines <- next:
+			queue[0].tail.next <- struct{}{}
+			queue = queue[1:]
+		case <-m.ended:
+		case <-m.Dying():
+			m.mu.Lock()
+			tails := m.tails
+			m.tails = map[string]*managedTail{}
+			m.mu.Unlock()
+			for _, mt := range tails {
+				mt.Stop()
+			}
+			return
+		}
+	}
+}
+
+// finished reports whether the Manager was closed and has no tails left.
+func (m *Manager) finished() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed && len(m.tails) == 0
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	tailTest, cleanup := NewTailTest("manager", t)
+	defer cleanup()
+	tailTest.CreateFile("chatty.log", strings.Repeat("chatty\n", 100))
+	tailTest.CreateFile("quiet.log", "quiet 1\nquiet 2\n")
+	tailTest.CreateFile("failing.log", "failure\n")
+
+	m := NewManager(Config{Logger: DiscardingLogger})
+	chatty, err := m.Add(filepath.Join(tailTest.path, "chatty.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := m.Add(filepath.Join(tailTest.path, "quiet.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Add(quiet.Filename); err == nil {
+		t.Error("expected an error when adding a file twice")
+	}
+	// A tail with its own configuration, failing after its first line.
+	failing, err := TailFile(filepath.Join(tailTest.path, "failing.log"), Config{Follow: true, Logger: DiscardingLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddTail(failing); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	// Let all the tails read their first line.
+	time.Sleep(100 * time.Millisecond)
+	var got []*MergedLine
+	for line := range m.Lines {
+		if line.Tail == failing {
+			failing.Kill(errors.New(line.Text))
+			continue
+		}
+		got = append(got, line)
+	}
+	if len(got) != 102 {
+		t.Fatalf("expected 102 lines, got %d", len(got))
+	}
+	// The quiet file is not held back by the chatty one.
+	quietLines := 0
+	for _, line := range got[:5] {
+		if line.Tail == quiet {
+			quietLines++
+		}
+	}
+	if quietLines != 2 {
+		t.Errorf("expected the 2 quiet lines in the first 5 lines, got %d", quietLines)
+	}
+	for i, line := range got {
+		if line.Tail != chatty && line.Tail != quiet {
+			t.Errorf("line %d has no source", i)
+		}
+	}
+
+	err = m.Wait()
+	merr, ok := err.(ManagerError)
+	if !ok || len(merr) != 1 || merr[failing.Filename] == nil {
+		t.Errorf("expected the error of failing.log, got %v", err)
+	}
+	if _, err := m.Add(quiet.Filename); err == nil {
+		t.Error("expected an error when adding to a stopped manager")
+	}
+}
+
+func TestManagerRemove(t *testing.T) {
+	tailTest, cleanup := NewTailTest("manager-remove", t)
+	defer cleanup()
+	tailTest.CreateFile("a.log", "a1\n")
+	tailTest.CreateFile("b.log", "b1\n")
+
+	m := NewManager(Config{Follow: true, Logger: DiscardingLogger})
+	for _, name := range []string{"a.log", "b.log"} {
+		if _, err := m.Add(filepath.Join(tailTest.path, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts := map[string]bool{}
+	for len(texts) < 2 {
+		texts[(<-m.Lines).Text] = true
+	}
+
+	if err := m.Remove(filepath.Join(tailTest.path, "a.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove(filepath.Join(tailTest.path, "a.log")); err == nil {
+		t.Error("expected an error when removing a file twice")
+	}
+	if len(m.Tails()) != 1 {
+		t.Errorf("expected 1 tail, got %d", len(m.Tails()))
+	}
+	// Give the tails time to watch for changes, like in reOpen.
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("a.log", "a2\n")
+	tailTest.AppendFile("b.log", "b2\n")
+	if line := <-m.Lines; line.Text != "b2" {
+		t.Errorf("expected b2, got %s", line.Text)
+	}
+	if err := m.Stop(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-m.Lines; ok {
+		t.Error("expected Lines to be closed")
+	}
+}
+
+func TestManagerTailLatest(t *testing.T) {
+	tailTest, cleanup := NewTailTest("manager-latest", t)
+	defer cleanup()
+	tailTest.CreateFile("app-1.log", "a\n")
+	tail, err := TailLatest(tailTest.path+"/app-*.log", ByName{}, Config{
+		Follow:       true,
+		Poll:         true,
+		PollInterval: 100 * time.Millisecond,
+		Logger:       DiscardingLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testManagerSwitch(t, tail, func() { tailTest.CreateFile("app-2.log", "b\n") })
+}
+
+func TestManagerFilenameTemplate(t *testing.T) {
+	tailTest, cleanup := NewTailTest("manager-template", t)
+	defer cleanup()
+	now := time.Now()
+	tailTest.CreateFile(ExpandTemplate("app-%S.log", now), "a\n")
+	tail, err := TailFile(tailTest.path+"/app-%S.log", Config{
+		Follow:           true,
+		Poll:             true,
+		PollInterval:     100 * time.Millisecond,
+		FilenameTemplate: true,
+		TemplateInterval: 100 * time.Millisecond,
+		Logger:           DiscardingLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testManagerSwitch(t, tail, func() {
+		tailTest.CreateFile(ExpandTemplate("app-%S.log", now.Add(time.Second)), "b\n")
+	})
+}
+
+// testManagerSwitch checks that the Manager stops once tail, having
+// switched to the file created by create, is killed.
+func testManagerSwitch(t *testing.T, tail *Tail, create func()) {
+	defer tail.Cleanup()
+	m := NewManager(Config{Logger: DiscardingLogger})
+	if err := m.AddTail(tail); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	next := func() string {
+		select {
+		case line := <-m.Lines:
+			return line.Text
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a line")
+			return ""
+		}
+	}
+	if text := next(); text != "a" {
+		t.Fatalf("expected a, got %s", text)
+	}
+	create()
+	if text := next(); text != "b" {
+		t.Fatalf("expected b, got %s", text)
+	}
+
+	tail.Kill(nil)
+	select {
+	case _, ok := <-m.Lines:
+		if ok {
+			t.Error("expected Lines to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the manager to stop")
+	}
+	if err := m.Wait(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"container/heap"
+	"time"
+
+	"gopkg.in/tomb.v1"
+)
+
+// MergedLine is a line of a Merger or a Manager, with the Tail it was read
+// by.
+type MergedLine struct {
+	*Line
+	Tail *Tail
+}
+
+// Merger merges the lines of several tails into one stream ordered by
+// Line.EventTime, which requires Config.TimestampExtractor.
+//
+// As files are written concurrently, the next line of each tail is held, and
+// the earliest one is sent once every other tail has a line held too, has
+// been quiet for lateness since its previous line was sent, or has stopped.
+// When none of the tails follows its file, tails are rather waited for until
+// they have a line or have stopped. As one line per tail is held, lines of a
+// file are kept in the order of the file, and the lines held do not grow with
+// the pace of the files. Lines of a tail that was quiet for longer than
+// lateness may still come out of order.
+type Merger struct {
+	Lines chan *MergedLine // A consumable channel of *MergedLine
+
+	lateness time.Duration
+	headOnly bool // Tails are not quiet, when none follows its file
+	sources  []*mergeSource
+	in       chan mergeInput
+	err      error
+
+	tomb.Tomb // provides: Done, Kill, Dying
+}
+
+type mergeSource struct {
+	tail *Tail
+	seen time.Time // When the tail was let send its next line
+	done bool
+
+	pending int           // Lines held, at most one
+	next    chan struct{} // Lets the tail send its next line
+}
+
+type mergeInput struct {
+	source int
+	line   *Line // nil when the tail stopped
+	err    error
+}
+
+// NewMerger starts merging the lines of tails, see Merger. Stopping the
+// Merger stops the tails.
+func NewMerger(lateness time.Duration, tails ...*Tail) *Merger {
+	m := &Merger{
+		Lines:    make(chan *MergedLine),
+		lateness: lateness,
+		in:       make(chan mergeInput),
+		headOnly: true,
+	}
+	for _, t := range tails {
+		if t.Follow {
+			m.headOnly = false
+		}
+	}
+	now := time.Now()
+	for _, t := range tails {
+		s := &mergeSource{tail: t, seen: now, next: make(chan struct{}, 1)}
+		s.next <- struct{}{}
+		m.sources = append(m.sources, s)
+	}
+	for i, t := range tails {
+		go m.receive(i, t)
+	}
+	go m.run()
+	return m
+}
+
+// MergeFiles tails the files with config and merges their lines, see Merger.
+// If config has no TimestampExtractor, NewTimestampExtractor with the default
+// layouts is used, and lines without timestamp follow the line above them.
+func MergeFiles(filenames []string, config Config, lateness time.Duration) (*Merger, error) {
+	if config.TimestampExtractor == nil {
+		config.TimestampExtractor, _ = NewTimestampExtractor()
+		config.TimestampFallback = FallbackPrevious
+	}
+	var tails []*Tail
+	for _, filename := range filenames {
+		t, err := TailFile(filename, config)
+		if err != nil {
+			for _, t := range tails {
+				t.Stop()
+			}
+			return nil, err
+		}
+		tails = append(tails, t)
+	}
+	return NewMerger(lateness, tails...), nil
+}
+
+// Stop stops merging and the tails. Otherwise, the Merger stops when all the
+// tails have, then Wait and Err return the first error of the tails.
+func (m *Merger) Stop() error {
+	m.Kill(nil)
+	return m.Wait()
+}
+
+func (m *Merger) receive(source int, t *Tail) {
+	next := m.sources[source].next
+	for {
+		select {
+		case <-next:
+		case <-m.Dying():
+			return
+		}
+		line, ok := <-t.Lines
+		if !ok {
+			break
+		}
+		select {
+		case m.in <- mergeInput{source: source, line: line}:
+		case <-m.Dying():
+			return
+		}
+	}
+	err := t.Wait()
+	if err == errStopAtEOF {
+		err = nil
+	}
+	select {
+	case m.in <- mergeInput{source: source, err: err}:
+	case <-m.Dying():
+	}
+}
+
+func (m *Merger) run() {
+	defer m.Done()
+	defer close(m.Lines)
+
+	var pending mergeHeap
+	seq := 0
+	for {
+		var wake time.Time
+		for len(pending) > 0 {
+			var ok bool
+			if ok, wake = m.releasable(time.Now()); !ok {
+				break
+			}
+			select {
+			case m.Lines <- pending[0].MergedLine:
+			case <-m.Dying():
+				m.stop()
+				return
+			}
+			item := heap.Pop(&pending).(*mergeItem)
+			item.source.pending--
+			item.source.seen = time.Now()
+			item.source.next <- struct{}{}
+		}
+		if len(pending) == 0 && m.stopped() {
+			m.Kill(m.err)
+			return
+		}
+
+		// Wait for a line, or for a tail to be quiet for long enough.
+		var timeout <-chan time.Time
+		if len(pending) > 0 && !wake.IsZero() {
+			timeout = time.After(time.Until(wake))
+		}
+		select {
+		case in := <-m.in:
+			s := m.sources[in.source]
+			if in.line == nil {
+				s.done = true
+				if in.err != nil && m.err == nil {
+					m.err = in.err
+				}
+				continue
+			}
+			seq++
+			s.pending++
+			heap.Push(&pending, &mergeItem{&MergedLine{in.line, s.tail}, s, seq})
+		case <-timeout:
+		case <-m.Dying():
+			m.stop()
+			return
+		}
+	}
+}
+
+// releasable reports whether the first of the lines held can be sent, that
+// is whether every tail has a line held, has stopped or, unless headOnly, has
+// been quiet for lateness. If not, it returns when a tail will be quiet.
+func (m *Merger) releasable(now time.Time) (ok bool, wake time.Time) {
+	ok = true
+	for _, s := range m.sources {
+		if s.done || s.pending > 0 {
+			continue
+		}
+		if m.headOnly {
+			return false, time.Time{}
+		}
+		if quiet := s.seen.Add(m.lateness); now.Before(quiet) {
+			if ok || quiet.Before(wake) {
+				wake = quiet
+			}
+			ok = false
+		}
+	}
+	return ok, wake
+}
+
+func (m *Merger) stopped() bool {
+	for _, s := range m.sources {
+		if !s.done {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Merger) stop() {
+	for _, s := range m.sources {
+		s.tail.Stop()
+	}
+}
+
+// mergeItem is a pending line. Lines with the same EventTime are kept in
+// the order they were received.
+type mergeItem struct {
+	*MergedLine
+	source *mergeSource
+	seq    int
+}
+
+type mergeHeap []*mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].EventTime.Equal(h[j].EventTime) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].EventTime.Before(h[j].EventTime)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mergedTexts(m *Merger, n int) []string {
+	var texts []string
+	for line := range m.Lines {
+		texts = append(texts, filepath.Base(line.Tail.Filename)+":"+line.Text)
+		if len(texts) == n {
+			break
+		}
+	}
+	return texts
+}
+
+func TestMergeFiles(t *testing.T) {
+	tailTest, cleanup := NewTailTest("merge-files", t)
+	defer cleanup()
+	tailTest.CreateFile("nginx.log", "2026-10-17T09:00:00Z GET /a\n2026-10-17T09:00:02Z GET /b\n")
+	tailTest.CreateFile("app.log", "2026-10-17T09:00:01Z handling /a\n  at main.go:12\n2026-10-17T09:00:03Z handling /b\n")
+	tailTest.CreateFile("db.log", "2026-10-17T09:00:01.5Z select a\n")
+
+	var filenames []string
+	for _, name := range []string{"nginx.log", "app.log", "db.log"} {
+		filenames = append(filenames, filepath.Join(tailTest.path, name))
+	}
+	m, err := MergeFiles(filenames, Config{Logger: DiscardingLogger}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(mergedTexts(m, -1), "\n")
+	want := `nginx.log:2026-10-17T09:00:00Z GET /a
+app.log:2026-10-17T09:00:01Z handling /a
+app.log:  at main.go:12
+db.log:2026-10-17T09:00:01.5Z select a
+nginx.log:2026-10-17T09:00:02Z GET /b
+app.log:2026-10-17T09:00:03Z handling /b`
+	if got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if err := m.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeFollow(t *testing.T) {
+	tailTest, cleanup := NewTailTest("merge-follow", t)
+	defer cleanup()
+	tailTest.CreateFile("a.log", "2026-10-17T09:00:01Z a1\n")
+	tailTest.CreateFile("b.log", "2026-10-17T09:00:00Z b1\n")
+	tailTest.CreateFile("quiet.log", "")
+
+	var filenames []string
+	for _, name := range []string{"a.log", "b.log", "quiet.log"} {
+		filenames = append(filenames, filepath.Join(tailTest.path, name))
+	}
+	m, err := MergeFiles(filenames, Config{Follow: true, Logger: DiscardingLogger}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	// The quiet file does not hold the others back for more than the lateness.
+	if got := strings.Join(mergedTexts(m, 2), " "); got != "b.log:2026-10-17T09:00:00Z b1 a.log:2026-10-17T09:00:01Z a1" {
+		t.Errorf("unexpected lines %s", got)
+	}
+	// A line written slightly late, by a tail that is not quiet, is put back
+	// in order.
+	tailTest.AppendFile("b.log", "2026-10-17T09:00:04Z b2\n")
+	if got := strings.Join(mergedTexts(m, 1), " "); got != "b.log:2026-10-17T09:00:04Z b2" {
+		t.Errorf("unexpected lines %s", got)
+	}
+	tailTest.AppendFile("a.log", "2026-10-17T09:00:06Z a2\n")
+	tailTest.AppendFile("b.log", "2026-10-17T09:00:05Z b3\n")
+	if got := strings.Join(mergedTexts(m, 2), " "); got != "b.log:2026-10-17T09:00:05Z b3 a.log:2026-10-17T09:00:06Z a2" {
+		t.Errorf("unexpected lines %s", got)
+	}
+}
+
+func TestMergeFilesHeadOnly(t *testing.T) {
+	testMergeReadAhead(t, "merge-head-only", false)
+}
+
+func TestMergeFollowReadAhead(t *testing.T) {
+	testMergeReadAhead(t, "merge-follow-read-ahead", true)
+}
+
+func testMergeReadAhead(t *testing.T, name string, follow bool) {
+	tailTest, cleanup := NewTailTest(name, t)
+	defer cleanup()
+	var big strings.Builder
+	for i := 0; i < 200; i++ {
+		big.WriteString("2026-10-17T09:00:01Z line\n")
+	}
+	tailTest.CreateFile("big.log", big.String())
+	tailTest.CreateFile("small.log", "2026-10-17T09:00:00Z line\n")
+
+	var filenames []string
+	for _, name := range []string{"big.log", "small.log"} {
+		filenames = append(filenames, filepath.Join(tailTest.path, name))
+	}
+	m, err := MergeFiles(filenames, Config{Follow: follow, Logger: DiscardingLogger}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	// The big file is not read ahead while the lines are not consumed.
+	<-time.After(200 * time.Millisecond)
+	if n := m.sources[0].tail.Stats().Lines; n > 2 {
+		t.Errorf("expected at most 2 lines read ahead, got %d", n)
+	}
+	n := -1
+	if follow {
+		n = 201
+	}
+	if texts := mergedTexts(m, n); len(texts) != 201 || texts[0] != "small.log:2026-10-17T09:00:00Z line" {
+		t.Errorf("unexpected lines %d %v", len(texts), texts)
+	}
+	if !follow {
+		if err := m.Wait(); err != nil {
+			t.Error(err)
+		}
+	}
+}
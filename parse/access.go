@@ -0,0 +1,102 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLog parses the access logs of Apache and Nginx in the Common Log
+// Format, or the Combined Log Format which adds the referer and user agent.
+// Fields that are "-" in the log are left out, and so is what follows the
+// user agent, such as the extra fields of custom Nginx formats.
+//
+// The fields are client, ident, user, time (time.Time), request, method,
+// path, protocol, status (int), bytes (int64), referer and user_agent.
+type AccessLog struct{}
+
+const accessLogLayout = "02/Jan/2006:15:04:05 -0700"
+
+var accessLogRegexp = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)` +
+	`(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?(?:\s|$)`)
+
+var errNotAccessLog = errors.New("parse: not an access log line")
+
+func (AccessLog) Parse(text string) (map[string]interface{}, error) {
+	m := accessLogRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil, errNotAccessLog
+	}
+	ts, err := time.Parse(accessLogLayout, m[4])
+	if err != nil {
+		return nil, errors.New("parse: invalid access log time " + m[4])
+	}
+
+	fields := map[string]interface{}{"time": ts}
+	setString(fields, "client", m[1])
+	setString(fields, "ident", m[2])
+	setString(fields, "user", m[3])
+
+	request := unescape(m[5])
+	setString(fields, "request", request)
+	if parts := strings.Fields(request); len(parts) >= 2 && len(parts) <= 3 {
+		fields["method"] = parts[0]
+		fields["path"] = parts[1]
+		if len(parts) == 3 {
+			fields["protocol"] = parts[2]
+		}
+	}
+
+	fields["status"], _ = strconv.Atoi(m[6])
+	if m[7] != "-" {
+		fields["bytes"], _ = strconv.ParseInt(m[7], 10, 64)
+	}
+	setString(fields, "referer", unescape(m[8]))
+	setString(fields, "user_agent", unescape(m[9]))
+	return fields, nil
+}
+
+// setString sets the field unless the value is empty or "-".
+func setString(fields map[string]interface{}, key, value string) {
+	if value != "" && value != "-" {
+		fields[key] = value
+	}
+}
+
+// unescape undoes the escaping of quoted strings in access logs: \" and \\
+// by Apache, \xHH by Nginx.
+func unescape(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'x':
+			if i+2 < len(s) {
+				if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					b.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+			}
+			b.WriteString(`\x`)
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Grok compiles grok patterns: regular expressions (RE2 syntax) that refer to
+// named patterns with %{NAME}, or %{NAME:field} to capture what NAME matched
+// as field. A type can be added to convert the captured text:
+// %{NUMBER:bytes:int} stores an int64 and %{NUMBER:took:float} a float64.
+//
+// A Grok starts with the patterns of BasePatterns, more can be added with
+// AddPattern and AddPatterns. Compiled patterns are cached. A Grok can be used
+// from several goroutines at once.
+type Grok struct {
+	mu       sync.Mutex
+	patterns map[string]string
+	cache    map[string]*GrokParser
+}
+
+// NewGrok returns a Grok knowing the patterns of BasePatterns.
+func NewGrok() *Grok {
+	g := &Grok{
+		patterns: make(map[string]string, len(BasePatterns)),
+		cache:    make(map[string]*GrokParser),
+	}
+	for name, pattern := range BasePatterns {
+		g.patterns[name] = pattern
+	}
+	return g
+}
+
+var grokNameRegexp = regexp.MustCompile(`^\w+$`)
+
+// AddPattern defines or replaces the pattern named name.
+func (g *Grok) AddPattern(name, pattern string) error {
+	if !grokNameRegexp.MatchString(name) {
+		return fmt.Errorf("grok: invalid pattern name %q", name)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.patterns[name] = pattern
+	// Compiled patterns may depend on the previous definition.
+	g.cache = make(map[string]*GrokParser)
+	return nil
+}
+
+// AddPatterns reads pattern definitions, one per line: the name of the
+// pattern, followed by spaces and the pattern. Empty lines and lines
+// starting with # are ignored.
+func (g *Grok) AddPatterns(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			return fmt.Errorf("grok: line %d: missing pattern for %s", num, line)
+		}
+		if err := g.AddPattern(line[:i], strings.TrimSpace(line[i:])); err != nil {
+			return fmt.Errorf("grok: line %d: %s", num, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// AddPatternsFromFile reads pattern definitions from a file, see AddPatterns.
+func (g *Grok) AddPatternsFromFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := g.AddPatterns(f); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	return nil
+}
+
+// Compile compiles a grok pattern into a parser of the fields it captures.
+func (g *Grok) Compile(pattern string) (*GrokParser, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.cache[pattern]; ok {
+		return p, nil
+	}
+
+	c := &grokCompiler{patterns: g.patterns, expanding: map[string]bool{}}
+	expr, err := c.expand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("grok: %s", err)
+	}
+	p := &GrokParser{re: re, fields: c.fields}
+	g.cache[pattern] = p
+	return p, nil
+}
+
+// GrokParser parses lines with a compiled grok pattern. It implements
+// tail.Parser.
+type GrokParser struct {
+	re     *regexp.Regexp
+	fields []grokField // By capture group, starting with the first one
+}
+
+type grokField struct {
+	name string
+	typ  string // "", "int" or "float"
+}
+
+var errNoMatch = errors.New("parse: line does not match the grok pattern")
+
+// Parse returns the fields captured in text. Fields in parts of the pattern
+// that did not take part in the match are left out.
+func (p *GrokParser) Parse(text string) (map[string]interface{}, error) {
+	m := p.re.FindStringSubmatchIndex(text)
+	if m == nil {
+		return nil, errNoMatch
+	}
+	fields := map[string]interface{}{}
+	for i, field := range p.fields {
+		start, end := m[2*i+2], m[2*i+3]
+		if start < 0 {
+			continue
+		}
+		if _, ok := fields[field.name]; ok {
+			continue
+		}
+		value := text[start:end]
+		switch field.typ {
+		case "int":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[field.name] = n
+				continue
+			}
+		case "float":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				fields[field.name] = f
+				continue
+			}
+		}
+		fields[field.name] = value
+	}
+	return fields, nil
+}
+
+// grokCompiler expands the references of a pattern into a regular expression.
+type grokCompiler struct {
+	patterns  map[string]string
+	expanding map[string]bool // Patterns being expanded, to detect cycles
+	fields    []grokField
+}
+
+var grokRefRegexp = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+func (c *grokCompiler) expand(pattern string) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, m := range grokRefRegexp.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(c.rawGroups(pattern[last:m[0]]))
+		last = m[1]
+
+		name := pattern[m[2]:m[3]]
+		def, ok := c.patterns[name]
+		if !ok {
+			return "", fmt.Errorf("grok: unknown pattern %s", name)
+		}
+		if c.expanding[name] {
+			return "", fmt.Errorf("grok: pattern %s refers to itself", name)
+		}
+
+		if m[4] < 0 {
+			b.WriteString("(?:")
+		} else {
+			field := grokField{name: pattern[m[4]:m[5]]}
+			if m[6] >= 0 {
+				field.typ = pattern[m[6]:m[7]]
+				if field.typ != "int" && field.typ != "float" {
+					return "", fmt.Errorf("grok: unknown type %s for field %s", field.typ, field.name)
+				}
+			}
+			c.fields = append(c.fields, field)
+			b.WriteString("(")
+		}
+		c.expanding[name] = true
+		expr, err := c.expand(def)
+		delete(c.expanding, name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(expr)
+		b.WriteString(")")
+	}
+	b.WriteString(c.rawGroups(pattern[last:]))
+	return b.String(), nil
+}
+
+// rawGroups turns the named groups of a plain regular expression, which
+// capture fields as well, into plain capture groups recorded in c.fields.
+// Other capture groups are made non-capturing.
+func (c *grokCompiler) rawGroups(expr string) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\' && i+1 < len(expr):
+			b.WriteString(expr[i : i+2])
+			i++
+		case expr[i] == '[':
+			// Parentheses in character classes are not groups.
+			end := classEnd(expr, i)
+			b.WriteString(expr[i:end])
+			i = end - 1
+		case strings.HasPrefix(expr[i:], "(?P<") || strings.HasPrefix(expr[i:], "(?<"):
+			start := strings.IndexByte(expr[i:], '<') + 1
+			end := strings.IndexByte(expr[i:], '>')
+			if end < 0 {
+				b.WriteString(expr[i:])
+				return b.String()
+			}
+			c.fields = append(c.fields, grokField{name: expr[i+start : i+end]})
+			b.WriteString("(")
+			i += end
+		case expr[i] == '(' && !strings.HasPrefix(expr[i:], "(?"):
+			b.WriteString("(?:")
+		default:
+			b.WriteByte(expr[i])
+		}
+	}
+	return b.String()
+}
+
+// classEnd returns the offset following the character class starting at
+// expr[start].
+func classEnd(expr string, start int) int {
+	i := start + 1
+	if i < len(expr) && expr[i] == '^' {
+		i++
+	}
+	if i < len(expr) && expr[i] == ']' {
+		i++
+	}
+	for ; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\':
+			i++
+		case expr[i] == '[' && strings.HasPrefix(expr[i:], "[:"):
+			if end := strings.Index(expr[i:], ":]"); end >= 0 {
+				i += end + 1
+			}
+		case expr[i] == ']':
+			return i + 1
+		}
+	}
+	return len(expr)
+}
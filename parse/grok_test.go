@@ -0,0 +1,133 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGrok(t *testing.T) {
+	g := NewGrok()
+	p, err := g.Compile(`^%{TIMESTAMP_ISO8601:time} \[%{LOGLEVEL:level}\] %{IP:client}(?: \((?P<user>\w+)\))? ` +
+		`%{UUID:request_id} took %{NUMBER:took:float}ms, sent %{INT:bytes:int} bytes( to %{URIPATHPARAM:path})?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := p.Parse("2026-10-17T09:30:00.123+02:00 [WARN] fe80::1 123e4567-e89b-12d3-a456-426614174000 took 1.5ms, sent 512 bytes to /a?b=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"time": "2026-10-17T09:30:00.123+02:00", "level": "WARN", "client": "fe80::1",
+		"request_id": "123e4567-e89b-12d3-a456-426614174000", "took": 1.5, "bytes": int64(512), "path": "/a?b=c",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	fields, err = p.Parse("2026-10-17 09:30:00Z [info] 10.0.0.1 (bob) 123e4567-e89b-12d3-a456-426614174000 took 2ms, sent 0 bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["user"] != "bob" || fields["client"] != "10.0.0.1" || fields["path"] != nil {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	if _, err := p.Parse("no match"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestGrokCombinedLog(t *testing.T) {
+	p, err := NewGrok().Compile("%{COMBINEDAPACHELOG}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := p.Parse(`127.0.0.1 - frank [10/Oct/2026:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "-" "curl/8.0"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["method"] != "GET" || fields["status"] != int64(200) || fields["timestamp"] != "10/Oct/2026:13:55:36 -0700" ||
+		fields["agent"] != `"curl/8.0"` || fields["request"] != nil {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestGrokPatterns(t *testing.T) {
+	g := NewGrok()
+	err := g.AddPatterns(strings.NewReader(`
+# Custom patterns
+QUEUE   queue-[0-9]+
+JOB     %{QUEUE:queue}/%{POSINT:job:int}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := g.Compile("started %{JOB}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2, _ := g.Compile("started %{JOB}"); p2 != p {
+		t.Error("expected the compiled pattern to be cached")
+	}
+	fields, err := p.Parse("job started queue-7/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields, map[string]interface{}{"queue": "queue-7", "job": int64(42)}) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	// Redefining a pattern drops the compiled ones using it.
+	g.AddPattern("QUEUE", `q[0-9]+`)
+	if p, _ = g.Compile("started %{JOB}"); p == nil {
+		t.Fatal("expected a parser")
+	}
+	if _, err := p.Parse("started queue-7/42"); err == nil {
+		t.Error("expected the new definition to be used")
+	}
+
+	g.AddPattern("LOOP", "a%{LOOP}")
+	for _, pattern := range []string{"%{LOOP}", "%{NOPE}", "%{INT:n:bool}", "%{INT:n} ("} {
+		if _, err := g.Compile(pattern); err == nil {
+			t.Errorf("expected an error for %s", pattern)
+		}
+	}
+	if err := g.AddPatterns(strings.NewReader("NAME_ONLY")); err == nil {
+		t.Error("expected an error for a definition without pattern")
+	}
+}
+
+func TestGrokBasePatterns(t *testing.T) {
+	g := NewGrok()
+	tests := map[string][]string{
+		"IPV4":              {"192.168.0.1", "8.8.8.8"},
+		"IPV6":              {"2001:db8::ff00:42:8329", "::1", "::ffff:10.0.0.1", "1:2:3:4:5:6:7:8"},
+		"HOSTNAME":          {"example.com", "db-1.internal"},
+		"SYSLOGTIMESTAMP":   {"Oct  7 09:30:00"},
+		"HTTPDATE":          {"10/Oct/2026:13:55:36 -0700"},
+		"TIMESTAMP_ISO8601": {"2026-10-17T09:30:00Z", "2026-10-17 09:30:00,123"},
+		"LOGLEVEL":          {"ERROR", "warning", "Information"},
+		"URI":               {"https://user@example.com:8443/a/b?c=d"},
+		"EPOCH":             {"1792229400", "1792229400123"},
+	}
+	for name, inputs := range tests {
+		p, err := g.Compile("^%{" + name + ":v}$")
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		for _, in := range inputs {
+			if fields, err := p.Parse(in); err != nil || fields["v"] != in {
+				t.Errorf("%s should match %q, got %v", name, in, fields)
+			}
+		}
+	}
+	for name := range BasePatterns {
+		if _, err := g.Compile("%{" + name + "}"); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Logfmt parses key=value pairs separated by spaces, values being quoted when
+// they contain spaces. Unquoted values are typed: integers become int64,
+// other numbers float64 and true/false bool. Keys without a value are set to
+// true. The values of the time, ts and timestamp keys are parsed as RFC 3339
+// timestamps; the first one found is also stored as "time".
+type Logfmt struct{}
+
+var errNoPairs = errors.New("parse: no key=value pairs")
+
+func (Logfmt) Parse(text string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	pairs := 0
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(text) && text[i] != '=' && text[i] != ' ' && text[i] != '\t' {
+			i++
+		}
+		key := text[start:i]
+		if key == "" || strings.ContainsRune(key, '"') {
+			return nil, errors.New("parse: invalid logfmt key at offset " + strconv.Itoa(start))
+		}
+		if i == len(text) || text[i] != '=' {
+			fields[key] = true
+			continue
+		}
+		i++ // =
+
+		if i < len(text) && text[i] == '"' {
+			end := quotedEnd(text, i)
+			if end < 0 {
+				return nil, errors.New("parse: unterminated logfmt value for " + key)
+			}
+			value, err := strconv.Unquote(text[i:end])
+			if err != nil {
+				return nil, errors.New("parse: invalid logfmt value for " + key)
+			}
+			fields[key] = value
+			i = end
+		} else {
+			start = i
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+				i++
+			}
+			fields[key] = typedValue(text[start:i])
+		}
+		pairs++
+	}
+	if pairs == 0 {
+		return nil, errNoPairs
+	}
+
+	for _, key := range []string{"time", "ts", "timestamp"} {
+		s, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			fields[key] = t
+			if _, ok := fields["time"].(time.Time); !ok {
+				fields["time"] = t
+			}
+		}
+	}
+	return fields, nil
+}
+
+// quotedEnd returns the offset following the quoted string starting at
+// text[start], or -1 if it is not terminated.
+func quotedEnd(text string, start int) int {
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func typedValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+// Package parse provides parsers turning the text of tailed lines into typed
+// fields, for use as tail.Config.Parser. The formats supported are logfmt,
+// Apache/Nginx access logs and syslog (RFC 3164 and RFC 5424).
+//
+// Event timestamps are stored as time.Time values in the "time" field.
+package parse
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nxadm/tail"
+)
+
+var parsers = map[string]func() tail.Parser{
+	"logfmt":   func() tail.Parser { return Logfmt{} },
+	"common":   func() tail.Parser { return AccessLog{} },
+	"combined": func() tail.Parser { return AccessLog{} },
+	"apache":   func() tail.Parser { return AccessLog{} },
+	"nginx":    func() tail.Parser { return AccessLog{} },
+	"syslog":   func() tail.Parser { return Syslog{} },
+	"rfc3164":  func() tail.Parser { return RFC3164{} },
+	"rfc5424":  func() tail.Parser { return RFC5424{} },
+}
+
+// ByName returns the parser for a format: logfmt, common, combined (or its
+// aliases apache and nginx), syslog, rfc3164 or rfc5424.
+func ByName(name string) (tail.Parser, error) {
+	p, ok := parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("parse: unknown format %q", name)
+	}
+	return p(), nil
+}
+
+// Names returns the formats known to ByName, sorted.
+func Names() []string {
+	var names []string
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
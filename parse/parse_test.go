@@ -0,0 +1,132 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLogfmt(t *testing.T) {
+	fields, err := Logfmt{}.Parse(`ts=2026-10-17T09:30:00Z level=info msg="user \"bob\" logged in" took=0.25 count=3 ok=true cached`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	want := map[string]interface{}{
+		"ts": ts, "time": ts, "level": "info", "msg": `user "bob" logged in`,
+		"took": 0.25, "count": int64(3), "ok": true, "cached": true,
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	for _, text := range []string{"", "just words", `msg="unterminated`, "=value"} {
+		if _, err := (Logfmt{}).Parse(text); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	fields, err := AccessLog{}.Parse(`127.0.0.1 - frank [10/Oct/2026:13:55:36 -0700] "GET /a.gif?q=\x22x\x22 HTTP/1.1" 200 2326 "http://example.com/" "Mozilla/5.0 (X11)" rt=0.003`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"client": "127.0.0.1", "user": "frank",
+		"time":    time.Date(2026, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		"request": `GET /a.gif?q="x" HTTP/1.1`, "method": "GET", "path": `/a.gif?q="x"`, "protocol": "HTTP/1.1",
+		"status": 200, "bytes": int64(2326), "referer": "http://example.com/", "user_agent": "Mozilla/5.0 (X11)",
+	}
+	if fields["time"].(time.Time).Equal(want["time"].(time.Time)) {
+		fields["time"] = want["time"]
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	// Common Log Format, without size
+	fields, err = AccessLog{}.Parse(`::1 - - [10/Oct/2026:13:55:36 +0000] "-" 400 -`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["status"] != 400 || fields["bytes"] != nil || fields["user"] != nil || fields["method"] != nil {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	if _, err := (AccessLog{}).Parse("level=info"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestRFC3164(t *testing.T) {
+	fields, err := Syslog{Location: time.UTC}.Parse("<34>Oct  5 22:14:15 mymachine su[230]: 'su root' failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := fields["time"].(time.Time)
+	if ts.Month() != time.October || ts.Day() != 5 || ts.Hour() != 22 || ts.Location() != time.UTC {
+		t.Errorf("unexpected time %s", ts)
+	}
+	delete(fields, "time")
+	want := map[string]interface{}{
+		"facility": 4, "severity": 2, "hostname": "mymachine", "app": "su", "pid": "230", "message": "'su root' failed",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	// As found in /var/log/syslog
+	fields, err = RFC3164{}.Parse("Oct 17 09:30:00 box kernel: eth0: link up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["app"] != "kernel" || fields["message"] != "eth0: link up" || fields["facility"] != nil {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestRFC5424(t *testing.T) {
+	fields, err := Syslog{}.Parse(`<165>1 2026-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appl\"ication"][other] ` + "\ufeff" + `An application event`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"facility": 20, "severity": 5, "version": 1,
+		"time":     time.Date(2026, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		"hostname": "mymachine.example.com", "app": "evntslog", "msgid": "ID47",
+		"structured_data": map[string]map[string]string{
+			"exampleSDID@32473": {"iut": "3", "eventSource": `Appl"ication`},
+			"other":             {},
+		},
+		"message": "An application event",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	fields, err = RFC5424{}.Parse("<13>1 - - - - - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 3 {
+		t.Errorf("expected only facility, severity and version, got %v", fields)
+	}
+
+	if _, err := (RFC5424{}).Parse(`<13>1 - - - - - [id key=unquoted] msg`); err == nil {
+		t.Error("expected an error for invalid structured data")
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := ByName(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := ByName("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package parse
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nxadm/tail/util"
+)
+
+// Syslog parses syslog messages in either format, see RFC3164 and RFC5424.
+type Syslog struct {
+	Location *time.Location // Time zone of RFC 3164 timestamps. If nil, time.Local is used
+}
+
+func (s Syslog) Parse(text string) (map[string]interface{}, error) {
+	if rfc5424Regexp.MatchString(text) {
+		return RFC5424{}.Parse(text)
+	}
+	return RFC3164{Location: s.Location}.Parse(text)
+}
+
+// RFC3164 parses BSD syslog messages, with or without the <PRI> part written
+// by the senders: "<34>Oct 11 22:14:15 host app[42]: message". As their
+// timestamps have no year, the year that puts them closest to the present
+// time is used.
+//
+// The fields are facility and severity (int, when PRI is present), time
+// (time.Time), hostname, app, pid (string) and message.
+type RFC3164 struct {
+	Location *time.Location // Time zone of the timestamps. If nil, time.Local is used
+}
+
+var rfc3164Regexp = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\s:\[]+)(?:\[([^\]]*)\])?:? ?(.*)$`)
+
+var errNotSyslog = errors.New("parse: not a syslog message")
+
+func (p RFC3164) Parse(text string) (map[string]interface{}, error) {
+	m := rfc3164Regexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil, errNotSyslog
+	}
+	fields := map[string]interface{}{}
+	if m[1] != "" {
+		if err := setPriority(fields, m[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	ts, err := time.ParseInLocation(time.Stamp, m[2], loc)
+	if err != nil {
+		return nil, errors.New("parse: invalid syslog time " + m[2])
+	}
+	fields["time"] = util.ClosestYear(ts, time.Now())
+
+	fields["hostname"] = m[3]
+	fields["app"] = m[4]
+	if m[5] != "" {
+		fields["pid"] = m[5]
+	}
+	fields["message"] = m[6]
+	return fields, nil
+}
+
+// RFC5424 parses syslog messages in the format of RFC 5424:
+// "<34>1 2026-10-11T22:14:15.003Z host app 42 ID47 [id key="value"] message".
+//
+// The fields are facility and severity (int), version (int), time
+// (time.Time), hostname, app, pid, msgid, structured_data
+// (map[string]map[string]string, keyed by SD-ID) and message. NILVALUEs are
+// left out.
+type RFC5424 struct{}
+
+var rfc5424Regexp = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) `)
+
+func (RFC5424) Parse(text string) (map[string]interface{}, error) {
+	m := rfc5424Regexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil, errNotSyslog
+	}
+	fields := map[string]interface{}{}
+	if err := setPriority(fields, m[1]); err != nil {
+		return nil, err
+	}
+	fields["version"], _ = strconv.Atoi(m[2])
+	if m[3] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, m[3])
+		if err != nil {
+			return nil, errors.New("parse: invalid syslog time " + m[3])
+		}
+		fields["time"] = ts
+	}
+	setString(fields, "hostname", m[4])
+	setString(fields, "app", m[5])
+	setString(fields, "pid", m[6])
+	setString(fields, "msgid", m[7])
+
+	rest := text[len(m[0]):]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		sd, n, err := parseStructuredData(rest)
+		if err != nil {
+			return nil, err
+		}
+		fields["structured_data"] = sd
+		rest = rest[n:]
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	rest = strings.TrimPrefix(rest, "\ufeff")
+	if rest != "" {
+		fields["message"] = rest
+	}
+	return fields, nil
+}
+
+var errStructuredData = errors.New("parse: invalid syslog structured data")
+
+// parseStructuredData parses the SD-ELEMENTs at the start of s, and returns
+// them with their length.
+func parseStructuredData(s string) (map[string]map[string]string, int, error) {
+	sd := map[string]map[string]string{}
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		params := map[string]string{}
+		sd[s[start:i]] = params
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+			start = i
+			for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != ']' && s[i] != '"' {
+				i++
+			}
+			name := s[start:i]
+			if name == "" || i+1 >= len(s) || s[i] != '=' || s[i+1] != '"' {
+				return nil, 0, errStructuredData
+			}
+			i += 2
+			var value strings.Builder
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, 0, errStructuredData
+			}
+			params[name] = value.String()
+			i++ // closing quote
+		}
+		if i == len(s) || s[i] != ']' {
+			return nil, 0, errStructuredData
+		}
+		i++
+	}
+	if i == 0 {
+		return nil, 0, errStructuredData
+	}
+	return sd, i, nil
+}
+
+func setPriority(fields map[string]interface{}, s string) error {
+	pri, err := strconv.Atoi(s)
+	if err != nil || pri > 191 {
+		return errors.New("parse: invalid syslog priority " + s)
+	}
+	fields["facility"] = pri / 8
+	fields["severity"] = pri % 8
+	return nil
+}
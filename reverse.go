@@ -0,0 +1,122 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Size of the blocks read by ReverseReader.
+const reverseBlockSize = 64 * 1024
+
+// ReverseReader reads the lines of a file backwards, from the last one, with
+// block reads from the end. Only the blocks holding the lines read are read.
+type ReverseReader struct {
+	r    io.ReaderAt
+	size int64
+	pos  int64  // Offset of the last line read, the lines before are unread
+	buf  []byte // The bytes before pos that were read
+
+	file io.Closer // File opened by OpenReverse
+}
+
+// NewReverseReader returns a ReverseReader reading the first size bytes of r
+// backwards.
+func NewReverseReader(r io.ReaderAt, size int64) *ReverseReader {
+	return &ReverseReader{r: r, size: size, pos: size}
+}
+
+// OpenReverse opens a file to read its lines backwards, from its current
+// end. Close closes the file.
+func OpenReverse(filename string) (*ReverseReader, error) {
+	f, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r := NewReverseReader(f, fi.Size())
+	r.file = f
+	return r, nil
+}
+
+// Close closes the file opened by OpenReverse.
+func (r *ReverseReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// ReadLine returns the text of the line before the lines already read,
+// without its line ending ("\n" or "\r\n"), and the offset at which it
+// starts. A last line without line ending is read like the others. ReadLine
+// returns io.EOF once the first line was read.
+func (r *ReverseReader) ReadLine() (string, int64, error) {
+	if r.pos == 0 {
+		return "", 0, io.EOF
+	}
+	if len(r.buf) == 0 {
+		if err := r.readBlock(); err != nil {
+			return "", 0, err
+		}
+	}
+	end := r.pos
+	if end < r.size || r.buf[len(r.buf)-1] == '\n' {
+		end-- // Line ending
+	}
+
+	// Look for the end of the previous line, reading blocks as needed.
+	start := int64(0)
+	searched := end
+	for {
+		bufStart := r.pos - int64(len(r.buf))
+		if i := bytes.LastIndexByte(r.buf[:searched-bufStart], '\n'); i >= 0 {
+			start = bufStart + int64(i) + 1
+			break
+		}
+		if bufStart == 0 {
+			break
+		}
+		searched = bufStart
+		if err := r.readBlock(); err != nil {
+			return "", 0, err
+		}
+	}
+
+	bufStart := r.pos - int64(len(r.buf))
+	text := strings.TrimSuffix(string(r.buf[start-bufStart:end-bufStart]), "\r")
+	r.buf = r.buf[:start-bufStart]
+	r.pos = start
+	return text, start, nil
+}
+
+// Offset returns the offset of the last line read, or the size of the file
+// if none was read.
+func (r *ReverseReader) Offset() int64 {
+	return r.pos
+}
+
+// readBlock reads the block preceding r.buf.
+func (r *ReverseReader) readBlock() error {
+	bufStart := r.pos - int64(len(r.buf))
+	n := int64(reverseBlockSize)
+	if n > bufStart {
+		n = bufStart
+	}
+	block := make([]byte, n, n+int64(len(r.buf)))
+	if read, err := r.r.ReadAt(block, bufStart-n); err != nil && !(err == io.EOF && int64(read) == n) {
+		if err == io.EOF {
+			// The file was truncated.
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	r.buf = append(block, r.buf...)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readReverse(t *testing.T, r *ReverseReader) ([]string, []int64) {
+	var texts []string
+	var offsets []int64
+	for {
+		text, offset, err := r.ReadLine()
+		if err == io.EOF {
+			return texts, offsets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, text)
+		offsets = append(offsets, offset)
+	}
+}
+
+func TestReverseReader(t *testing.T) {
+	long := strings.Repeat("x", 2*reverseBlockSize+10)
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"", nil},
+		{"\n", []string{""}},
+		{"one\ntwo\n", []string{"two", "one"}},
+		{"one\ntwo", []string{"two", "one"}},
+		{"one\r\ntwo\r\n\r\n", []string{"", "two", "one"}},
+		{"one\n" + long + "\nthree", []string{"three", long, "one"}},
+		{long + "\n" + long, []string{long, long}},
+	}
+	for _, test := range tests {
+		r := NewReverseReader(strings.NewReader(test.content), int64(len(test.content)))
+		texts, offsets := readReverse(t, r)
+		if !reflect.DeepEqual(texts, test.want) {
+			t.Errorf("%.20q: expected %d lines %.40q, got %d lines %.40q", test.content, len(test.want), test.want, len(texts), texts)
+			continue
+		}
+		for i, offset := range offsets {
+			if !strings.HasPrefix(test.content[offset:], texts[i]) || (offset > 0 && test.content[offset-1] != '\n') {
+				t.Errorf("%.20q: wrong offset %d for line %.20q", test.content, offset, texts[i])
+			}
+		}
+		if r.Offset() != 0 {
+			t.Errorf("%.20q: expected to end at offset 0, got %d", test.content, r.Offset())
+		}
+	}
+}
+
+func TestOpenReverse(t *testing.T) {
+	tailTest, cleanup := NewTailTest("open-reverse", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\n")
+
+	r, err := OpenReverse(filepath.Join(tailTest.path, "test.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if texts, _ := readReverse(t, r); !reflect.DeepEqual(texts, []string{"world", "hello"}) {
+		t.Errorf("unexpected lines %q", texts)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RotationScheme finds the files a file was rotated to, see Config.Rotation.
+type RotationScheme interface {
+	// Siblings returns the rotated files of filename, newest first.
+	Siblings(filename string) ([]string, error)
+}
+
+// NumericRotation finds the files rotated like logrotate does by default:
+// app.log.1 is the newest, then app.log.2 and so on, compressed or not
+// (app.log.2.gz).
+type NumericRotation struct{}
+
+// Siblings returns the rotated files of filename, newest first.
+func (NumericRotation) Siblings(filename string) ([]string, error) {
+	type numbered struct {
+		n    int
+		name string
+	}
+	var files []numbered
+	err := eachSibling(filename, ".", func(name, suffix string) {
+		if n, err := strconv.Atoi(suffix); err == nil && n >= 0 {
+			files = append(files, numbered{n, name})
+		}
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].n < files[j].n })
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, err
+}
+
+// DateExtRotation finds the files rotated like logrotate does with dateext:
+// app.log-20240131, compressed or not (app.log-20240131.gz). The dates must
+// sort in time order, like the default format %Y%m%d.
+type DateExtRotation struct {
+	Separator string // Between the file name and the date, "-" if empty
+}
+
+// Siblings returns the rotated files of filename, newest first.
+func (r DateExtRotation) Siblings(filename string) ([]string, error) {
+	sep := r.Separator
+	if sep == "" {
+		sep = "-"
+	}
+	var names, dates []string
+	err := eachSibling(filename, sep, func(name, suffix string) {
+		names = append(names, name)
+		dates = append(dates, suffix)
+	})
+	sort.Sort(sort.Reverse(byDate{names, dates}))
+	return names, err
+}
+
+type byDate struct{ names, dates []string }
+
+func (d byDate) Len() int           { return len(d.names) }
+func (d byDate) Less(i, j int) bool { return d.dates[i] < d.dates[j] }
+func (d byDate) Swap(i, j int) {
+	d.names[i], d.names[j] = d.names[j], d.names[i]
+	d.dates[i], d.dates[j] = d.dates[j], d.dates[i]
+}
+
+// eachSibling calls fn with the files of the directory of filename named
+// after it, followed by sep, and with what follows without ".gz".
+func eachSibling(filename, sep string, fn func(name, suffix string)) error {
+	infos, err := ioutil.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(filename) + sep
+	for _, fi := range infos {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), prefix) {
+			continue
+		}
+		suffix := strings.TrimSuffix(strings.TrimPrefix(fi.Name(), prefix), ".gz")
+		if suffix != "" {
+			fn(filepath.Join(filepath.Dir(filename), fi.Name()), suffix)
+		}
+	}
+	return nil
+}
+
+// openRotated opens a rotated file, decompressing it if it ends with ".gz".
+func openRotated(name string) (io.ReadCloser, error) {
+	f, err := OpenFile(name)
+	if err != nil || !strings.HasSuffix(name, ".gz") {
+		return f, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
+
+// rotatedFingerprint returns the fingerprint of the first size bytes of a
+// rotated file, decompressed.
+func rotatedFingerprint(name string, size int64) (Checkpoint, error) {
+	r, err := openRotated(name)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	defer r.Close()
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Checkpoint{}, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return Checkpoint{Fingerprint: hex.EncodeToString(sum[:]), FingerprintSize: int64(n)}, nil
+}
+
+// catchUp looks for the rotated file the checkpoint c was saved for, and
+// delivers its lines after the checkpoint, then the lines of the newer
+// rotated files. It returns false if there is no such file.
+func (tail *Tail) catchUp(c *Checkpoint) (bool, error) {
+	siblings, err := tail.Rotation.Siblings(tail.name)
+	if err != nil {
+		return false, err
+	}
+	found := -1
+	for i, name := range siblings {
+		fp, err := rotatedFingerprint(name, c.FingerprintSize)
+		if err != nil {
+			tail.Logger.Printf("Unable to read %s: %s", name, err)
+			continue
+		}
+		if fp.Fingerprint == c.Fingerprint && fp.FingerprintSize == c.FingerprintSize {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		return false, nil
+	}
+
+	offset := c.Offset
+	for i := found; i >= 0; i-- {
+		tail.Logger.Printf("Catching up with %s from offset %d ...", siblings[i], offset)
+		if err := tail.readRotated(siblings[i], offset); err != nil {
+			return true, err
+		}
+		select {
+		case <-tail.Dying():
+			if tail.Err() != errStopAtEOF {
+				return true, nil
+			}
+		default:
+		}
+		offset = 0
+	}
+	tail.lineNum = 0
+	return true, nil
+}
+
+// rotatedSource counts the bytes read from a rotated file, for Tell.
+type rotatedSource struct {
+	r      io.Reader
+	offset int64
+}
+
+func (src *rotatedSource) Read(p []byte) (int, error) {
+	n, err := src.r.Read(p)
+	src.offset += int64(n)
+	return n, err
+}
+
+func (src *rotatedSource) tell(tail *Tail) int64 {
+	tail.lk.Lock()
+	defer tail.lk.Unlock()
+	return src.offset - int64(tail.reader.Buffered())
+}
+
+// readRotated delivers the lines of a rotated file from offset.
+func (tail *Tail) readRotated(name string, offset int64) error {
+	fp, err := rotatedFingerprint(name, fingerprintSize)
+	if err != nil {
+		return err
+	}
+	r, err := openRotated(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+		return err
+	}
+
+	src := &rotatedSource{r: r, offset: offset}
+	tail.lk.Lock()
+	tail.rotated = src
+	tail.lk.Unlock()
+	defer func() {
+		tail.lk.Lock()
+		tail.rotated = nil
+		tail.lk.Unlock()
+	}()
+	if tail.acker != nil {
+		tail.acker.start(fp, offset)
+	}
+	tail.lineNum = 0
+	tail.setReader(src)
+
+	for {
+		line, err := tail.readLine()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && tail.lineBuf != nil && tail.lineBuf.Len() > 0 {
+			// A rotated file does not grow: its last line is whole.
+			line = tail.lineBuf.String()
+			tail.lineBuf.Reset()
+		}
+		if line != "" || err == nil {
+			tail.sendLine(line, false)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		select {
+		case <-tail.Dying():
+			if tail.Err() != errStopAtEOF {
+				return nil
+			}
+		default:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRotationSiblings(t *testing.T) {
+	tailTest, cleanup := NewTailTest("rotation-siblings", t)
+	defer cleanup()
+	for _, name := range []string{"app.log", "app.log.1", "app.log.10.gz", "app.log.2.gz", "app.log.old",
+		"app.log-20240130.gz", "app.log-20240201", "app.log-20240131", "other.log.1"} {
+		tailTest.CreateFile(name, "")
+	}
+	filename := tailTest.path + "/app.log"
+	join := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(tailTest.path, name)
+		}
+		return names
+	}
+
+	siblings, err := NumericRotation{}.Siblings(filename)
+	if expected := join("app.log.1", "app.log.2.gz", "app.log.10.gz"); err != nil || !reflect.DeepEqual(siblings, expected) {
+		t.Errorf("expected %v, got %v (%v)", expected, siblings, err)
+	}
+	siblings, err = DateExtRotation{}.Siblings(filename)
+	if expected := join("app.log-20240201", "app.log-20240131", "app.log-20240130.gz"); err != nil || !reflect.DeepEqual(siblings, expected) {
+		t.Errorf("expected %v, got %v (%v)", expected, siblings, err)
+	}
+}
+
+func TestRotationCatchUp(t *testing.T) {
+	tailTest, cleanup := NewTailTest("rotation-catch-up", t)
+	defer cleanup()
+	tailTest.CreateFile("app.log", "a\nb\nc\n")
+	config := Config{
+		Ack:          true,
+		Checkpointer: NewFileCheckpointer(tailTest.path + "/checkpoints.json"),
+		Rotation:     NumericRotation{},
+		Logger:       DiscardingLogger,
+	}
+	lines := readAll(tailTest.StartTail("app.log", config))
+	lines[0].Ack()
+
+	// Rotated twice since, the oldest file being compressed.
+	f, err := os.Create(tailTest.path + "/app.log.2.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write([]byte("a\nb\nc\n"))
+	w.Close()
+	f.Close()
+	tailTest.RemoveFile("app.log")
+	tailTest.CreateFile("app.log.1", "d\ne")
+	tailTest.CreateFile("app.log", "f\n")
+
+	tail := tailTest.StartTail("app.log", config)
+	lines = readAll(tail)
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+		line.Ack()
+	}
+	if expected := []string{"b", "c", "d", "e", "f"}; !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("expected %v, got %v", expected, texts)
+	}
+	if lines[0].SeekInfo.Offset != 4 || lines[0].Num != 1 || lines[4].Num != 1 {
+		t.Errorf("unexpected line %+v or %+v", lines[0], lines[4])
+	}
+	if c, err := config.Checkpointer.Load(tailTest.path + "/app.log"); err != nil || c == nil || c.Offset != 2 || c.FingerprintSize != 2 {
+		t.Errorf("unexpected checkpoint %+v (%v)", c, err)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Below this many bytes, TimeOffset reads lines one by one instead of
+// bisecting further.
+const timeScanSize = 64 * 1024
+
+// SeekTime returns the position of the first line of the file logged at or
+// after t, to be used as Config.Location. See TimeOffset.
+func SeekTime(filename string, t time.Time, extractor TimestampExtractor) (*SeekInfo, error) {
+	f, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset, err := TimeOffset(f, fi.Size(), t, extractor)
+	if err != nil {
+		return nil, err
+	}
+	return &SeekInfo{Offset: offset, Whence: io.SeekStart}, nil
+}
+
+// TimeOffset returns the offset of the first line of r logged at or after t,
+// or size if there is none. Timestamps are found by the extractor, or by
+// NewTimestampExtractor with the default layouts if it is nil. Lines without
+// timestamp are considered part of the line above them.
+//
+// The offset is found by a binary search, which reads little of large files
+// but assumes that their lines are in chronological order.
+func TimeOffset(r io.ReaderAt, size int64, t time.Time, extractor TimestampExtractor) (int64, error) {
+	if extractor == nil {
+		extractor, _ = NewTimestampExtractor()
+	}
+	lo, hi := int64(0), size // The line is found between lo, a line start, and hi
+	for hi-lo > timeScanSize {
+		mid := lo + (hi-lo)/2
+		start, err := nextLineStart(r, mid, size)
+		if err != nil {
+			return 0, err
+		}
+
+		found := false
+		err = readLines(r, start, size, func(offset, next int64, text string) bool {
+			if offset >= hi {
+				return false
+			}
+			ts, ok := extractor.Extract(text, nil)
+			if !ok {
+				return true
+			}
+			found = true
+			if ts.Before(t) {
+				lo = next
+			} else {
+				hi = offset
+			}
+			return false
+		})
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			// No timestamp between mid and hi: the line is before mid, or
+			// it is the one at hi, found by the scan below either way.
+			hi = mid
+		}
+	}
+
+	result := size
+	err := readLines(r, lo, size, func(offset, next int64, text string) bool {
+		if ts, ok := extractor.Extract(text, nil); ok && !ts.Before(t) {
+			result = offset
+			return false
+		}
+		return true
+	})
+	return result, err
+}
+
+// nextLineStart returns the offset of the first line starting at or after
+// offset.
+func nextLineStart(r io.ReaderAt, offset, size int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, offset-1, size-offset+1))
+	skipped := int64(0)
+	for {
+		chunk, err := br.ReadSlice('\n')
+		skipped += int64(len(chunk))
+		switch err {
+		case nil:
+			return offset - 1 + skipped, nil
+		case io.EOF:
+			return size, nil
+		case bufio.ErrBufferFull:
+		default:
+			return 0, err
+		}
+	}
+}
+
+// readLines calls fn with the offset of each line of r from offset, the
+// offset of the next line and the text of the line, until fn returns false.
+func readLines(r io.ReaderAt, offset, size int64, fn func(offset, next int64, text string) bool) error {
+	br := bufio.NewReader(io.NewSectionReader(r, offset, size-offset))
+	for offset < size {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		next := offset + int64(len(line))
+		if !fn(offset, next, strings.TrimRight(line, "\r\n")) || err == io.EOF {
+			return nil
+		}
+		offset = next
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	*strings.Reader
+	read int
+}
+
+func (r *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	r.read += n
+	return n, err
+}
+
+func TestTimeOffset(t *testing.T) {
+	start := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	offsets := map[time.Time]int64{}
+	for i := 0; i < 20000; i++ {
+		// Two lines per second, every tenth one followed by a stack trace.
+		ts := start.Add(time.Duration(i/2) * time.Second)
+		if _, ok := offsets[ts]; !ok {
+			offsets[ts] = int64(b.Len())
+		}
+		fmt.Fprintf(&b, "%s INFO request %d\n", ts.Format(time.RFC3339), i)
+		if i%10 == 0 {
+			b.WriteString("  at main.handle(main.go:42)\n  at main.main(main.go:12)\n")
+		}
+	}
+	text := b.String()
+	size := int64(len(text))
+	e, _ := NewTimestampExtractor()
+
+	tests := map[time.Time]int64{
+		start.Add(-time.Hour):      0,
+		start:                      0,
+		start.Add(5 * time.Second): offsets[start.Add(5*time.Second)],
+		start.Add(5*time.Second + time.Millisecond): offsets[start.Add(6*time.Second)],
+		start.Add(4321 * time.Second):               offsets[start.Add(4321*time.Second)],
+		start.Add(9999 * time.Second):               offsets[start.Add(9999*time.Second)],
+		start.Add(10000 * time.Second):              size,
+	}
+	for ts, want := range tests {
+		r := &countingReader{Reader: strings.NewReader(text)}
+		got, err := TimeOffset(r, size, ts, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: expected offset %d, got %d", ts, want, got)
+		}
+		if r.read > int(size)/4 {
+			t.Errorf("%s: read %d bytes of %d", ts, r.read, size)
+		}
+	}
+}
+
+func TestTimeOffsetWithoutTimestamps(t *testing.T) {
+	text := strings.Repeat("no timestamp here\n", 10000) + "2026-10-17T09:00:00Z last\n"
+	got, err := TimeOffset(strings.NewReader(text), int64(len(text)), time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(text) - len("2026-10-17T09:00:00Z last\n")); got != want {
+		t.Errorf("expected offset %d, got %d", want, got)
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	tailTest, cleanup := NewTailTest("seek-time", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "2026-10-17 08:59:59 a\n2026-10-17 09:00:00 b\n  trace\n2026-10-17 09:00:01 c\n")
+
+	e, _ := NewTimestampExtractor()
+	e.Location = time.UTC
+	location, err := SeekTime(tailTest.path+"/test.txt", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail := tailTest.StartTail("test.txt", Config{Location: location})
+	var got []string
+	for line := range tail.Lines {
+		got = append(got, line.Text)
+	}
+	if strings.Join(got, "|") != "2026-10-17 09:00:00 b|  trace|2026-10-17 09:00:01 c" {
+		t.Errorf("unexpected lines %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// timestamp returns the time at which the line of the record was read.
+func timestamp(r *Record) time.Time {
+	if r.Line != nil && !r.Line.Time.IsZero() {
+		return r.Line.Time
+	}
+	return time.Now()
+}
+
+// LokiEncoder encodes batches for the Loki push API
+// (POST /loki/api/v1/push). Records are grouped in one stream per file.
+//
+// Options of the loki+http(s) schemes: label.<name>=<value> adds a static
+// label, filename_label names the label holding the file name ("filename"
+// by default, empty to omit it) and tenant sets the X-Scope-OrgID header.
+type LokiEncoder struct {
+	Labels        map[string]string // Static labels of every stream
+	FilenameLabel string            // If not empty, label holding the file name
+}
+
+func (e *LokiEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (e *LokiEncoder) Encode(w io.Writer, batch []*Record) error {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	byFile := map[string]*stream{}
+
+	for _, r := range batch {
+		s := byFile[r.Filename]
+		if s == nil {
+			labels := make(map[string]string, len(e.Labels)+1)
+			for name, value := range e.Labels {
+				labels[name] = value
+			}
+			if e.FilenameLabel != "" {
+				labels[e.FilenameLabel] = r.Filename
+			}
+			s = &stream{Stream: labels}
+			byFile[r.Filename] = s
+			streams = append(streams, s)
+		}
+		ts := strconv.FormatInt(timestamp(r).UnixNano(), 10)
+		s.Values = append(s.Values, [2]string{ts, r.Text})
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		Streams []*stream `json:"streams"`
+	}{streams})
+}
+
+// BulkEncoder encodes batches for the Elasticsearch (and OpenSearch) _bulk
+// API. Every record becomes a document with the fields @timestamp, message,
+// log.file.path and log.offset.
+//
+// Options of the elasticsearch+http(s) schemes: index (default "logs") and
+// action ("index" or "create", the latter being required by data streams).
+type BulkEncoder struct {
+	Index  string
+	Action string
+}
+
+func (e *BulkEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+type bulkDocument struct {
+	Timestamp string `json:"@timestamp"`
+	Message   string `json:"message"`
+	Log       struct {
+		File struct {
+			Path string `json:"path"`
+		} `json:"file"`
+		Offset int64 `json:"offset"`
+	} `json:"log"`
+}
+
+func (e *BulkEncoder) Encode(w io.Writer, batch []*Record) error {
+	enc := json.NewEncoder(w)
+	action := map[string]map[string]string{e.Action: {"_index": e.Index}}
+	for _, r := range batch {
+		doc := bulkDocument{
+			Timestamp: timestamp(r).Format(time.RFC3339Nano),
+			Message:   r.Text,
+		}
+		doc.Log.File.Path = r.Filename
+		if r.Line != nil {
+			doc.Log.Offset = r.Line.SeekInfo.Offset
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckResponse reports the documents that were rejected, as the _bulk API
+// answers with 200 OK even when some of them fail.
+func (e *BulkEncoder) CheckResponse(body []byte) error {
+	var resp struct {
+		Errors bool                                     `json:"errors"`
+		Items  []map[string]struct{ Error interface{} } `json:"items"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("sink: invalid _bulk response: %s", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	failed := 0
+	var first interface{}
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				if failed == 0 {
+					first = result.Error
+				}
+				failed++
+			}
+		}
+	}
+	if failed == 0 {
+		return errors.New("sink: _bulk request reported errors")
+	}
+	return fmt.Errorf("sink: %d of %d documents rejected, first error: %v", failed, len(resp.Items), first)
+}
+
+// NDJSONEncoder encodes batches as newline delimited JSON objects with the
+// fields time, filename, num, offset and text.
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (NDJSONEncoder) Encode(w io.Writer, batch []*Record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range batch {
+		obj := struct {
+			Time     string `json:"time"`
+			Filename string `json:"filename"`
+			Num      int    `json:"num"`
+			Offset   int64  `json:"offset"`
+			Text     string `json:"text"`
+		}{Time: timestamp(r).Format(time.RFC3339Nano), Filename: r.Filename, Text: r.Text}
+		if r.Line != nil {
+			obj.Num, obj.Offset = r.Line.Num, r.Line.SeekInfo.Offset
+		}
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+)
+
+func init() {
+	Register("file", func(u *url.URL) (Sink, error) {
+		q := query{Values: u.Query()}
+		maxSize := q.size("max_size", 0)
+		maxFiles := q.int("max_files", 0)
+		if q.err != nil {
+			return nil, q.err
+		}
+		return NewFileSink(path(u), maxSize, maxFiles)
+	})
+}
+
+type fileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+// NewFileSink returns a Sink appending one record per line to the file at
+// path. When maxSize is non-zero, the file is rotated before it would grow
+// beyond maxSize bytes: path becomes path.1, path.1 becomes path.2 and so on,
+// keeping at most maxFiles rotated files.
+func NewFileSink(path string, maxSize int64, maxFiles int) (Sink, error) {
+	s := &fileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.size = f, fi.Size()
+	return nil
+}
+
+func (s *fileSink) Write(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	msg := r.Text + "\n"
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(msg)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.WriteString(msg)
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	if s.maxFiles > 0 {
+		for i := s.maxFiles - 1; i > 0; i-- {
+			err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
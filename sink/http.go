@@ -0,0 +1,402 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Encoder turns a batch of records into the body of an HTTP request.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, batch []*Record) error
+}
+
+// ResponseChecker can be implemented by an Encoder whose API reports
+// failures in the body of successful responses.
+type ResponseChecker interface {
+	CheckResponse(body []byte) error
+}
+
+// HTTPConfig is used to specify how records are shipped over HTTP.
+type HTTPConfig struct {
+	URL     string       // Endpoint the batches are POSTed to
+	Encoder Encoder      // Payload format of the batches
+	Header  http.Header  // Extra request headers, e.g. for authentication
+	Client  *http.Client // If nil, http.DefaultClient is used
+	Gzip    bool         // Compress request bodies
+
+	// Batching. A batch is sent when it holds BatchSize records or about
+	// BatchBytes of text, or when its oldest record waited FlushInterval.
+	BatchSize     int           // Defaults to 500
+	BatchBytes    int           // Defaults to 1MiB
+	FlushInterval time.Duration // Defaults to one second
+
+	// MaxPending bounds the memory used: once MaxPending records wait to be
+	// batched, Write blocks. Defaults to 10000.
+	MaxPending int
+
+	// Failed requests are retried with an exponential backoff between
+	// MinBackoff and MaxBackoff (defaults: 500ms and 30s), or as asked by a
+	// Retry-After header. MaxRetries limits the number of retries of a
+	// batch; when zero, batches are retried until the sink is closed.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// FlushTimeout limits how long Close waits for pending records to be
+	// delivered. Defaults to five seconds.
+	FlushTimeout time.Duration
+
+	// OnAck is called, in order, with every batch accepted by the server. It
+	// can be used to checkpoint the position of the records.
+	OnAck func(batch []*Record)
+	// OnError is called with every batch that is dropped, either because of
+	// a permanent error or because it ran out of retries.
+	OnError func(err error, batch []*Record)
+}
+
+// StatusError is returned for unsuccessful HTTP responses.
+type StatusError struct {
+	StatusCode int
+	Body       string
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sink: HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether sending the request again may succeed.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// rejectedError wraps the errors reported by a ResponseChecker.
+type rejectedError struct {
+	error
+}
+
+type httpSink struct {
+	cfg HTTPConfig
+
+	queue   chan *Record
+	closing chan struct{} // Closed by Close
+	done    chan struct{} // Closed when run returns
+	once    sync.Once
+
+	ctx    context.Context // Canceled when pending records should be dropped
+	cancel context.CancelFunc
+}
+
+// NewHTTPSink returns a Sink shipping batches of records over HTTP.
+func NewHTTPSink(cfg HTTPConfig) (Sink, error) {
+	if cfg.URL == "" || cfg.Encoder == nil {
+		return nil, fmt.Errorf("sink: URL and Encoder are required")
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = 1 << 20
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 10000
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = defaultFlushTimeout
+	}
+
+	s := &httpSink{
+		cfg:     cfg,
+		queue:   make(chan *Record, cfg.MaxPending),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+func (s *httpSink) Write(r *Record) error {
+	select {
+	case <-s.closing:
+		return ErrClosed
+	default:
+	}
+	select {
+	case s.queue <- r:
+		return nil
+	case <-s.closing:
+		return ErrClosed
+	}
+}
+
+func (s *httpSink) Close() error {
+	s.once.Do(func() {
+		close(s.closing)
+		select {
+		case <-s.done:
+		case <-time.After(s.cfg.FlushTimeout):
+			s.cancel()
+			<-s.done
+		}
+		s.cancel()
+	})
+	return nil
+}
+
+func (s *httpSink) run() {
+	defer close(s.done)
+
+	var batch []*Record
+	size := 0
+	var deadline <-chan time.Time
+	flush := func() {
+		if len(batch) > 0 {
+			s.send(batch)
+		}
+		batch, size, deadline = nil, 0, nil
+	}
+	add := func(r *Record) {
+		if len(batch) == 0 {
+			deadline = time.After(s.cfg.FlushInterval)
+		}
+		batch = append(batch, r)
+		size += len(r.Text)
+		if len(batch) >= s.cfg.BatchSize || size >= s.cfg.BatchBytes {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case r := <-s.queue:
+			add(r)
+		case <-deadline:
+			flush()
+		case <-s.closing:
+			for len(s.queue) > 0 {
+				add(<-s.queue)
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// send delivers a batch, retrying as configured.
+func (s *httpSink) send(batch []*Record) {
+	body, err := s.encode(batch)
+	if err != nil {
+		s.failed(err, batch)
+		return
+	}
+
+	backoff := s.cfg.MinBackoff
+	for retries := 0; ; retries++ {
+		err := s.post(body)
+		if err == nil {
+			if s.cfg.OnAck != nil {
+				s.cfg.OnAck(batch)
+			}
+			return
+		}
+
+		wait := backoff
+		if _, ok := err.(rejectedError); ok {
+			s.failed(err, batch)
+			return
+		}
+		if se, ok := err.(*StatusError); ok {
+			if !se.Temporary() {
+				s.failed(err, batch)
+				return
+			}
+			if se.retryAfter > 0 {
+				wait = se.retryAfter
+			}
+		}
+		if s.cfg.MaxRetries > 0 && retries >= s.cfg.MaxRetries {
+			s.failed(err, batch)
+			return
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-s.ctx.Done():
+			s.failed(err, batch)
+			return
+		}
+		if backoff *= 2; backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+func (s *httpSink) failed(err error, batch []*Record) {
+	if s.cfg.OnError != nil {
+		s.cfg.OnError(err, batch)
+	}
+}
+
+func (s *httpSink) encode(batch []*Record) ([]byte, error) {
+	var buf bytes.Buffer
+	if !s.cfg.Gzip {
+		err := s.cfg.Encoder.Encode(&buf, batch)
+		return buf.Bytes(), err
+	}
+	zw := gzip.NewWriter(&buf)
+	if err := s.cfg.Encoder.Encode(zw, batch); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *httpSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(s.ctx)
+	for key, values := range s.cfg.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", s.cfg.Encoder.ContentType())
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		se := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.retryAfter = time.Duration(secs) * time.Second
+		}
+		return se
+	}
+	if rc, ok := s.cfg.Encoder.(ResponseChecker); ok {
+		if err := rc.CheckResponse(respBody); err != nil {
+			// The request was processed, sending it again would
+			// duplicate what was accepted.
+			return rejectedError{err}
+		}
+	}
+	return nil
+}
+
+func init() {
+	for _, format := range []string{"loki", "elasticsearch", "ndjson"} {
+		for _, scheme := range []string{"http", "https"} {
+			format, scheme := format, scheme
+			Register(format+"+"+scheme, func(u *url.URL) (Sink, error) {
+				return openHTTP(format, scheme, u)
+			})
+		}
+	}
+}
+
+var httpOptions = map[string]bool{
+	"batch_size": true, "batch_bytes": true, "flush_interval": true, "max_pending": true,
+	"max_retries": true, "gzip": true, "tenant": true, "filename_label": true,
+	"index": true, "action": true,
+}
+
+// openHTTP creates the HTTP sinks selected by the loki+, elasticsearch+ and
+// ndjson+ schemes. Options common to all of them are batch_size,
+// batch_bytes, flush_interval, max_pending, max_retries and gzip, the others
+// are described with each Encoder.
+func openHTTP(format, scheme string, u *url.URL) (Sink, error) {
+	q := query{Values: u.Query()}
+	cfg := HTTPConfig{
+		BatchSize:     q.int("batch_size", 0),
+		BatchBytes:    int(q.size("batch_bytes", 0)),
+		FlushInterval: q.duration("flush_interval", 0),
+		MaxPending:    q.int("max_pending", 0),
+		MaxRetries:    q.int("max_retries", 0),
+		Gzip:          q.str("gzip", "false") == "true",
+		Header:        http.Header{},
+	}
+
+	switch format {
+	case "loki":
+		labels := map[string]string{}
+		for key := range q.Values {
+			if strings.HasPrefix(key, "label.") {
+				labels[strings.TrimPrefix(key, "label.")] = q.Get(key)
+			}
+		}
+		cfg.Encoder = &LokiEncoder{Labels: labels, FilenameLabel: q.str("filename_label", "filename")}
+		if tenant := q.Get("tenant"); tenant != "" {
+			cfg.Header.Set("X-Scope-OrgID", tenant)
+		}
+	case "elasticsearch":
+		cfg.Encoder = &BulkEncoder{Index: q.str("index", "logs"), Action: q.str("action", "index")}
+	case "ndjson":
+		cfg.Encoder = NDJSONEncoder{}
+	}
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	// The options consumed above and the credentials are not part of the
+	// endpoint, other query parameters are passed along.
+	params := u.Query()
+	for key := range params {
+		if httpOptions[key] || strings.HasPrefix(key, "label.") {
+			params.Del(key)
+		}
+	}
+	endpoint := *u
+	endpoint.Scheme = scheme
+	endpoint.RawQuery = params.Encode()
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(u.User.Username(), password)
+		cfg.Header.Set("Authorization", req.Header.Get("Authorization"))
+		endpoint.User = nil
+	}
+	cfg.URL = endpoint.String()
+	cfg.OnError = func(err error, batch []*Record) {
+		Logger.Printf("Dropped %d records for %s: %s", len(batch), cfg.URL, err)
+	}
+
+	return NewHTTPSink(cfg)
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector stands in for a log ingestion endpoint. It answers with the
+// given statuses first, then with 200.
+type collector struct {
+	mu       sync.Mutex
+	statuses []int
+	response string
+	requests []*http.Request
+	bodies   []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := ioutil.ReadAll(body)
+
+	c.mu.Lock()
+	c.requests = append(c.requests, r)
+	c.bodies = append(c.bodies, string(data))
+	status := http.StatusOK
+	if len(c.statuses) > 0 {
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+	}
+	c.mu.Unlock()
+
+	w.WriteHeader(status)
+	io.WriteString(w, c.response)
+}
+
+func (c *collector) received() ([]*http.Request, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests, c.bodies
+}
+
+func TestLokiSink(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var acked []*Record
+	s, err := Open("loki+" + srv.URL + "/loki/api/v1/push?label.job=gotail&tenant=ops&gzip=true&batch_size=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.(*httpSink).cfg.OnAck = func(batch []*Record) { acked = append(acked, batch...) }
+
+	for _, text := range []string{"one", "two", "three"} {
+		s.Write(record(text))
+	}
+	s.Close()
+
+	requests, bodies := c.received()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if r := requests[0]; r.URL.Path != "/loki/api/v1/push" || r.URL.RawQuery != "" || r.Header.Get("X-Scope-OrgID") != "ops" {
+		t.Errorf("unexpected request %s %v", r.URL, r.Header)
+	}
+	want := `{"streams":[{"stream":{"filename":"test.log","job":"gotail"},"values":[` +
+		`["1792229400000000000","one"],["1792229400000000000","two"],["1792229400000000000","three"]]}]}` + "\n"
+	if bodies[0] != want {
+		t.Errorf("expected %s, got %s", want, bodies[0])
+	}
+	if len(acked) != 3 || acked[2].Text != "three" {
+		t.Errorf("expected the 3 records to be acknowledged, got %d", len(acked))
+	}
+}
+
+func TestHTTPSinkRetries(t *testing.T) {
+	c := &collector{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	acked := make(chan []*Record, 1)
+	s, err := NewHTTPSink(HTTPConfig{
+		URL:           srv.URL,
+		Encoder:       NDJSONEncoder{},
+		FlushInterval: 10 * time.Millisecond,
+		MinBackoff:    time.Millisecond,
+		OnAck:         func(batch []*Record) { acked <- batch },
+		OnError:       func(err error, batch []*Record) { t.Errorf("unexpected error: %s", err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Write(record("hello"))
+
+	select {
+	case batch := <-acked:
+		if len(batch) != 1 || batch[0].Text != "hello" {
+			t.Errorf("unexpected batch %v", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not acknowledged")
+	}
+	if requests, _ := c.received(); len(requests) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(requests))
+	}
+}
+
+func TestHTTPSinkPermanentError(t *testing.T) {
+	c := &collector{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var failed []*Record
+	s, _ := NewHTTPSink(HTTPConfig{
+		URL:        srv.URL,
+		Encoder:    NDJSONEncoder{},
+		MinBackoff: time.Millisecond,
+		OnError:    func(err error, batch []*Record) { failed = batch },
+	})
+	s.Write(record("hello"))
+	s.Close()
+
+	if requests, _ := c.received(); len(requests) != 1 {
+		t.Errorf("expected no retries, got %d requests", len(requests))
+	}
+	if len(failed) != 1 {
+		t.Errorf("expected the batch to be reported as failed")
+	}
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	c := &collector{response: `{"errors":true,"items":[{"create":{"status":201}},{"create":{"error":{"type":"mapper_parsing_exception"}}}]}`}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var failure error
+	s, err := Open("elasticsearch+" + srv.URL + "/_bulk?index=app&action=create&refresh=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.(*httpSink).cfg.OnError = func(err error, batch []*Record) { failure = err }
+	s.Write(record("one"))
+	s.Write(record("two"))
+	s.Close()
+
+	requests, bodies := c.received()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if q := requests[0].URL.RawQuery; q != "refresh=true" {
+		t.Errorf("expected unknown options to be passed along, got %q", q)
+	}
+	if ct := requests[0].Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	lines := bufio.NewScanner(strings.NewReader(bodies[0]))
+	var got []map[string]interface{}
+	for lines.Scan() {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj)
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 2 actions and 2 documents, got %d lines", len(got))
+	}
+	if got[0]["create"].(map[string]interface{})["_index"] != "app" || got[3]["message"] != "two" {
+		t.Errorf("unexpected payload %s", bodies[0])
+	}
+	if failure == nil || !strings.Contains(failure.Error(), "1 of 2 documents rejected") {
+		t.Errorf("expected the rejected document to be reported, got %v", failure)
+	}
+}
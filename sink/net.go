@@ -0,0 +1,219 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBuffer       = 1000
+	defaultTimeout      = 10 * time.Second
+	defaultFlushTimeout = 5 * time.Second
+	minBackoff          = 100 * time.Millisecond
+	maxBackoff          = 30 * time.Second
+)
+
+func init() {
+	for _, network := range []string{"tcp", "tcp4", "tcp6", "unix"} {
+		network := network
+		Register(network, func(u *url.URL) (Sink, error) {
+			return openStream(network, u, lineFormat)
+		})
+	}
+	for _, network := range []string{"udp", "udp4", "udp6", "unixgram"} {
+		network := network
+		Register(network, func(u *url.URL) (Sink, error) {
+			return openDatagram(network, u, textFormat)
+		})
+	}
+}
+
+// lineFormat frames records with a newline, for stream connections.
+func lineFormat(r *Record) []byte {
+	return []byte(r.Text + "\n")
+}
+
+// textFormat sends the bare text, for datagrams.
+func textFormat(r *Record) []byte {
+	return []byte(r.Text)
+}
+
+func address(network string, u *url.URL) string {
+	if network == "unix" || network == "unixgram" {
+		return path(u)
+	}
+	return u.Host
+}
+
+func openStream(network string, u *url.URL, format func(*Record) []byte) (Sink, error) {
+	q := query{Values: u.Query()}
+	buffer := q.int("buffer", defaultBuffer)
+	timeout := q.duration("timeout", defaultTimeout)
+	if q.err != nil {
+		return nil, q.err
+	}
+	return NewStreamSink(network, address(network, u), buffer, timeout, format), nil
+}
+
+func openDatagram(network string, u *url.URL, format func(*Record) []byte) (Sink, error) {
+	q := query{Values: u.Query()}
+	timeout := q.duration("timeout", defaultTimeout)
+	if q.err != nil {
+		return nil, q.err
+	}
+	return NewDatagramSink(network, address(network, u), timeout, format)
+}
+
+type streamSink struct {
+	network string
+	addr    string
+	timeout time.Duration
+	format  func(*Record) []byte
+
+	queue   chan []byte
+	closing chan struct{} // Closed by Close
+	abort   chan struct{} // Closed when pending messages should be dropped
+	done    chan struct{} // Closed when run returns
+	once    sync.Once
+}
+
+// NewStreamSink returns a Sink forwarding records over a stream connection
+// ("tcp", "unix"), formatted with format. Up to buffer records are queued
+// while the connection is (re-)established, after which Write blocks. Lost
+// connections are re-established with an exponential backoff. Close waits a
+// few seconds for the queued records to be delivered.
+func NewStreamSink(network, addr string, buffer int, timeout time.Duration, format func(*Record) []byte) Sink {
+	s := &streamSink{
+		network: network,
+		addr:    addr,
+		timeout: timeout,
+		format:  format,
+		queue:   make(chan []byte, buffer),
+		closing: make(chan struct{}),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *streamSink) Write(r *Record) error {
+	msg := s.format(r)
+	select {
+	case <-s.closing:
+		return ErrClosed
+	default:
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	case <-s.closing:
+		return ErrClosed
+	}
+}
+
+func (s *streamSink) Close() error {
+	s.once.Do(func() {
+		close(s.closing)
+		select {
+		case <-s.done:
+		case <-time.After(defaultFlushTimeout):
+			close(s.abort)
+			<-s.done
+		}
+	})
+	return nil
+}
+
+func (s *streamSink) run() {
+	defer close(s.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	backoff := minBackoff
+	for {
+		var msg []byte
+		select {
+		case msg = <-s.queue:
+		case <-s.closing:
+			if len(s.queue) > 0 {
+				continue
+			}
+			return
+		}
+
+		for {
+			var err error
+			if conn == nil {
+				conn, err = net.DialTimeout(s.network, s.addr, s.timeout)
+			}
+			if err == nil {
+				conn.SetWriteDeadline(time.Now().Add(s.timeout))
+				if _, err = conn.Write(msg); err == nil {
+					backoff = minBackoff
+					break
+				}
+				conn.Close()
+				conn = nil
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-s.abort:
+				return
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+type datagramSink struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	timeout time.Duration
+	format  func(*Record) []byte
+}
+
+// NewDatagramSink returns a Sink sending each record, formatted with format,
+// as a datagram ("udp", "unixgram").
+func NewDatagramSink(network, addr string, timeout time.Duration, format func(*Record) []byte) (Sink, error) {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &datagramSink{conn: conn, timeout: timeout, format: format}, nil
+}
+
+func (s *datagramSink) Write(r *Record) error {
+	msg := s.format(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrClosed
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *datagramSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+// Package sink provides destinations to which tailed lines can be forwarded:
+// standard output, files with size-based rotation, TCP/UDP and Unix sockets,
+// and syslog servers. Sinks are selected with URL-like specifications (see
+// Open), new kinds of sinks can be added with Register.
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+// ErrClosed is returned when writing to a closed Sink.
+var ErrClosed = errors.New("sink: closed")
+
+// Logger reports the failures of sinks created by Open that deliver records
+// in the background, such as dropped HTTP batches.
+var Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// Record is a single line handed to a Sink.
+type Record struct {
+	Filename string     // The file the line was read from
+	Line     *tail.Line // The line and its metadata
+	Text     string     // The text to write, usually Line.Text
+}
+
+// Sink is a destination for records. Write may be called from several
+// goroutines at once.
+type Sink interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// Opener creates a Sink from a parsed specification.
+type Opener func(u *url.URL) (Sink, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = map[string]Opener{}
+)
+
+// Register makes a kind of Sink available to Open under the URL scheme.
+// Registering a scheme twice replaces the previous Opener.
+func Register(scheme string, open Opener) {
+	openersMu.Lock()
+	openers[scheme] = open
+	openersMu.Unlock()
+}
+
+// Schemes returns the registered URL schemes, sorted.
+func Schemes() []string {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+	schemes := make([]string, 0, len(openers))
+	for scheme := range openers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open creates the Sink described by spec. "-" and "stdout" write to the
+// standard output, plain paths append to a file. Anything else is a URL
+// whose scheme selects the kind of Sink, e.g.:
+//
+//	file:/var/log/copy.log?max_size=10M&max_files=5
+//	tcp://collector:5170?buffer=10000
+//	udp://collector:5170
+//	unix:///run/collector.sock
+//	unixgram:///run/collector.sock
+//	syslog+udp://loghost:514?format=rfc3164&facility=local0&tag=app
+//	syslog+unixgram:///dev/log
+func Open(spec string) (Sink, error) {
+	switch {
+	case spec == "-" || spec == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case !strings.Contains(spec, "://") && !strings.HasPrefix(spec, "file:"):
+		return NewFileSink(spec, 0, 0)
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	openersMu.RLock()
+	open := openers[u.Scheme]
+	openersMu.RUnlock()
+	if open == nil {
+		return nil, fmt.Errorf("sink: unknown scheme %q in %q", u.Scheme, spec)
+	}
+	return open(u)
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a Sink writing one record per line to w.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Write(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, r.Text+"\n")
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+type multiSink []Sink
+
+// Multi returns a Sink that duplicates its records to all the given sinks.
+// The first error is returned, but every sink is written to.
+func Multi(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return multiSink(sinks)
+}
+
+func (m multiSink) Write(r *Record) error {
+	var first error
+	for _, s := range m {
+		if err := s.Write(r); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiSink) Close() error {
+	var first error
+	for _, s := range m {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// ParseSize parses a size in bytes with an optional K, M or G suffix (powers
+// of 1024).
+func ParseSize(s string) (int64, error) {
+	num, mult := s, int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'k', 'K':
+			mult = 1 << 10
+		case 'm', 'M':
+			mult = 1 << 20
+		case 'g', 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			num = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("sink: invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// query gives typed access to the options of a sink specification.
+type query struct {
+	url.Values
+	err error
+}
+
+func (q *query) str(key, def string) string {
+	if v := q.Get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (q *query) int(key string, def int) int {
+	v := q.Get(key)
+	if v == "" || q.err != nil {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		q.err = fmt.Errorf("sink: invalid %s %q", key, v)
+	}
+	return n
+}
+
+func (q *query) size(key string, def int64) int64 {
+	v := q.Get(key)
+	if v == "" || q.err != nil {
+		return def
+	}
+	n, err := ParseSize(v)
+	if err != nil {
+		q.err = err
+	}
+	return n
+}
+
+func (q *query) duration(key string, def time.Duration) time.Duration {
+	v := q.Get(key)
+	if v == "" || q.err != nil {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		q.err = fmt.Errorf("sink: invalid %s %q", key, v)
+	}
+	return d
+}
+
+// path returns the path of a file: or unix: specification.
+func path(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	if u.Host != "" {
+		// unix://relative/path.sock
+		return u.Host + u.Path
+	}
+	return u.Path
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+func record(text string) *Record {
+	return &Record{
+		Filename: "test.log",
+		Line:     &tail.Line{Text: text, Time: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)},
+		Text:     text,
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"0": 0, "10": 10, "2k": 2048, "3M": 3 << 20, "1G": 1 << 30}
+	for in, want := range tests {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "-1", "1T"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestOpenUnknownScheme(t *testing.T) {
+	if _, err := Open("gopher://host"); err == nil {
+		t.Error("expected an error for an unknown scheme")
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "out.log")
+
+	s, err := Open("file:" + name + "?max_size=12&max_files=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"one", "two", "three", "four", "five", "six"} {
+		if err := s.Write(record(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		name:        "five\nsix\n",
+		name + ".1": "three\nfour\n",
+		name + ".2": "one\ntwo\n",
+	}
+	for file, want := range expected {
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: expected %q, got %q", file, want, got)
+		}
+	}
+	if _, err := os.Stat(name + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 rotated files")
+	}
+}
+
+func TestStreamSinkReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s, err := Open("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := acceptAfter(ln, func() { s.Write(record("hello")) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	if line, _ := r.ReadString('\n'); line != "hello\n" {
+		t.Errorf("expected hello, got %q", line)
+	}
+
+	// Drop the connection: the sink must reconnect and deliver what follows.
+	conn.Close()
+	conn, err = acceptAfter(ln, func() {
+		for i := 0; i < 20; i++ {
+			s.Write(record("again"))
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if line, _ := bufio.NewReader(conn).ReadString('\n'); line != "again\n" {
+		t.Errorf("expected again, got %q", line)
+	}
+}
+
+func acceptAfter(ln net.Listener, write func()) (net.Conn, error) {
+	go write()
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	return ln.Accept()
+}
+
+func TestDatagramSink(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s, err := Open("udp://" + pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(record("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "hello" {
+		t.Errorf("expected hello, got %q", got)
+	}
+}
+
+func TestUnixSyslogSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available")
+	}
+	dir, err := ioutil.TempDir("", "sink-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "syslog.sock")
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s, err := Open("syslog+unix://" + sock + "?facility=local0&severity=err&tag=app&hostname=box")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go s.Write(record("hello"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got string
+	r := bufio.NewReader(conn)
+	for !strings.HasSuffix(got, "hello") {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += string(b)
+	}
+	// Octet-counted <16*8+3>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+	prefix := "<131>1 2026-10-17T09:30:00.000000Z box app "
+	if i := strings.IndexByte(got, ' '); got[:i] != strconv.Itoa(len(got)-i-1) || !strings.HasPrefix(got[i+1:], prefix) {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &Syslog{Facility: 1, Severity: 6, Hostname: "box", Tag: "app", PID: 42}
+	got := string(s.Format(record("hello")))
+	if want := "<14>Oct 17 09:30:00 box app[42]: hello"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	s.RFC5424 = true
+	got = string(s.Format(record("hello")))
+	if want := "<14>1 2026-10-17T09:30:00.000000Z box app 42 - - hello"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
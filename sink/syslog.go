@@ -0,0 +1,143 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var severities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3,
+	"warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+func init() {
+	for _, network := range []string{"tcp", "tcp4", "tcp6", "unix"} {
+		network := network
+		Register("syslog+"+network, func(u *url.URL) (Sink, error) {
+			format, err := syslogFormat(u, true)
+			if err != nil {
+				return nil, err
+			}
+			return openStream(network, u, format)
+		})
+	}
+	for _, network := range []string{"udp", "udp4", "udp6", "unixgram"} {
+		network := network
+		Register("syslog+"+network, func(u *url.URL) (Sink, error) {
+			format, err := syslogFormat(u, false)
+			if err != nil {
+				return nil, err
+			}
+			return openDatagram(network, u, format)
+		})
+	}
+}
+
+// Syslog describes how records are framed as syslog messages.
+type Syslog struct {
+	RFC5424  bool   // Use the RFC 5424 format instead of the BSD (RFC 3164) one
+	Facility int    // Facility code, e.g. 1 for "user"
+	Severity int    // Severity code, e.g. 6 for "info"
+	Hostname string // Reported host name
+	Tag      string // The APP-NAME (RFC 5424) or TAG (RFC 3164)
+	PID      int    // Reported process ID, omitted when zero
+}
+
+// Format formats the record as a syslog message, using the time at which the
+// line was read.
+func (s *Syslog) Format(r *Record) []byte {
+	pri := s.Facility*8 + s.Severity
+	ts := time.Now()
+	if r.Line != nil && !r.Line.Time.IsZero() {
+		ts = r.Line.Time
+	}
+
+	if s.RFC5424 {
+		procID := "-"
+		if s.PID != 0 {
+			procID = strconv.Itoa(s.PID)
+		}
+		return []byte(fmt.Sprintf("<%d>1 %s %s %s %s - - %s",
+			pri, ts.Format("2006-01-02T15:04:05.000000Z07:00"),
+			nilValue(s.Hostname), nilValue(s.Tag), procID, r.Text))
+	}
+
+	tag := s.Tag
+	if s.PID != 0 {
+		tag += "[" + strconv.Itoa(s.PID) + "]"
+	}
+	return []byte(fmt.Sprintf("<%d>%s %s %s: %s",
+		pri, ts.Format(time.Stamp), s.Hostname, tag, r.Text))
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogFormat builds the framing of the syslog+ schemes from their options:
+// format (rfc5424 or rfc3164), facility, severity, hostname, tag and, for
+// stream connections, framing (octet-counting or newline, see RFC 6587).
+func syslogFormat(u *url.URL, stream bool) (func(*Record) []byte, error) {
+	q := query{Values: u.Query()}
+	hostname, _ := os.Hostname()
+	s := &Syslog{
+		Hostname: q.str("hostname", hostname),
+		Tag:      q.str("tag", filepath.Base(os.Args[0])),
+		PID:      os.Getpid(),
+	}
+
+	switch format := strings.ToLower(q.str("format", "rfc5424")); format {
+	case "rfc5424":
+		s.RFC5424 = true
+	case "rfc3164", "bsd":
+	default:
+		return nil, fmt.Errorf("sink: unknown syslog format %q", format)
+	}
+
+	var ok bool
+	if s.Facility, ok = facilities[q.str("facility", "user")]; !ok {
+		return nil, fmt.Errorf("sink: unknown syslog facility %q", q.Get("facility"))
+	}
+	if s.Severity, ok = severities[q.str("severity", "info")]; !ok {
+		return nil, fmt.Errorf("sink: unknown syslog severity %q", q.Get("severity"))
+	}
+
+	if !stream {
+		return s.Format, nil
+	}
+
+	defaultFraming := "newline"
+	if s.RFC5424 {
+		defaultFraming = "octet-counting"
+	}
+	switch framing := q.str("framing", defaultFraming); framing {
+	case "octet-counting":
+		return func(r *Record) []byte {
+			msg := s.Format(r)
+			return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}, nil
+	case "newline":
+		return func(r *Record) []byte {
+			return append(s.Format(r), '\n')
+		}, nil
+	default:
+		return nil, fmt.Errorf("sink: unknown syslog framing %q", framing)
+	}
+}
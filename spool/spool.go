@@ -0,0 +1,497 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+// Package spool provides a queue of records kept on disk, in segment files,
+// so that a writer is not held back by a slower reader. The position of the
+// reader is committed to a cursor file, replaced atomically: after a crash,
+// the records read since the last Commit are read again.
+//
+// The tail package spools lines with Config.SpoolDir.
+package spool
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrCanceled is returned by Read and Write when they are canceled.
+var ErrCanceled = errors.New("spool: canceled")
+
+// ErrClosed is returned when writing to a spool after CloseWrite or Close.
+var ErrClosed = errors.New("spool: closed")
+
+const (
+	defaultMaxSize = 64 * 1024 * 1024
+	headerSize     = 8 // Length and CRC-32 of the record
+	segmentExt     = ".seg"
+	cursorName     = "cursor"
+)
+
+// Options are the limits of a Spool.
+type Options struct {
+	// MaxSize is the number of bytes of uncommitted records above which Write
+	// waits for the reader. If zero, 64MB is used. A single record larger
+	// than MaxSize is written once the spool is empty.
+	MaxSize int64
+	// SegmentSize is the size above which a new segment file is started.
+	// Segments are removed once their records are committed. If zero,
+	// MaxSize/4 is used.
+	SegmentSize int64
+}
+
+// Spool is a queue of records on disk, for one writer and one reader.
+type Spool struct {
+	dir  string
+	opts Options
+
+	mu   sync.Mutex
+	w    *os.File // Last segment
+	wSeg int64
+	wOff int64
+	r    *os.File // Segment being read
+	rSeg int64
+	rOff int64
+	cSeg int64 // Committed position
+	cOff int64
+
+	pending     int64 // Bytes from the committed position to the end
+	uncommitted int64 // Bytes read since the committed position
+	closed      bool  // No more writes
+
+	last    int64 // Size of the last record read, for UnreadRecord
+	lastSeg int64 // Position of the last record read
+	lastOff int64
+
+	readable chan struct{}
+	writable chan struct{}
+}
+
+type cursor struct {
+	Segment int64
+	Offset  int64
+}
+
+// Open opens the spool kept in dir, creating it if needed. Records that were
+// not committed are read first.
+func Open(dir string, opts Options) (*Spool, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = opts.MaxSize / 4
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		dir:      dir,
+		opts:     opts,
+		readable: make(chan struct{}, 1),
+		writable: make(chan struct{}, 1),
+	}
+	if err := s.recover(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover finds the committed position and the end of the last segment,
+// dropping a record that was partly written.
+func (s *Spool) recover() error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	c, err := s.loadCursor()
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		segments = []int64{c.Segment + 1}
+		c = cursor{Segment: c.Segment + 1}
+	}
+	if c.Segment < segments[0] || c.Segment > segments[len(segments)-1] {
+		c = cursor{Segment: segments[0]}
+	}
+
+	// Segments before the committed one were fully read.
+	for _, seg := range segments {
+		if seg < c.Segment {
+			os.Remove(s.segmentPath(seg))
+		}
+	}
+
+	s.wSeg = segments[len(segments)-1]
+	s.w, err = os.OpenFile(s.segmentPath(s.wSeg), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	start := int64(0)
+	if c.Segment == s.wSeg {
+		start = c.Offset
+	}
+	s.wOff, err = validEnd(s.w, start)
+	if err != nil {
+		return err
+	}
+	if err := s.w.Truncate(s.wOff); err != nil {
+		return err
+	}
+	if _, err := s.w.Seek(s.wOff, io.SeekStart); err != nil {
+		return err
+	}
+	if c.Segment == s.wSeg && c.Offset > s.wOff {
+		c.Offset = s.wOff
+	}
+
+	s.cSeg, s.cOff = c.Segment, c.Offset
+	s.rSeg, s.rOff = c.Segment, c.Offset
+	for seg := c.Segment; seg <= s.wSeg; seg++ {
+		fi, err := os.Stat(s.segmentPath(seg))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		size := fi.Size()
+		if seg == s.wSeg {
+			size = s.wOff
+		}
+		s.pending += size
+	}
+	s.pending -= c.Offset
+	return nil
+}
+
+// validEnd returns the offset following the last complete record of f,
+// starting at offset.
+func validEnd(f *os.File, offset int64) (int64, error) {
+	for {
+		_, n, err := readRecord(f, offset)
+		if err == io.EOF || err == errCorrupt {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += n
+	}
+}
+
+var errCorrupt = errors.New("spool: corrupt record")
+
+// readRecord reads the record at offset in f and returns it with its size.
+// It returns io.EOF at the end of the file and errCorrupt for a record that
+// was partly written.
+func readRecord(f *os.File, offset int64) ([]byte, int64, error) {
+	var header [headerSize]byte
+	n, err := f.ReadAt(header[:], offset)
+	if n == 0 && err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if n < headerSize {
+		if err == io.EOF {
+			err = errCorrupt
+		}
+		return nil, 0, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(header[:4]))
+	if _, err := f.ReadAt(data, offset+headerSize); err != nil {
+		if err == io.EOF {
+			err = errCorrupt
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, 0, errCorrupt
+	}
+	return data, headerSize + int64(len(data)), nil
+}
+
+// Write appends a record, waiting while the spool holds MaxSize bytes of
+// uncommitted records, unless cancel is closed.
+func (s *Spool) Write(record []byte, cancel <-chan struct{}) error {
+	size := headerSize + int64(len(record))
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return ErrClosed
+		}
+		if s.pending == 0 || s.pending+size <= s.opts.MaxSize {
+			break
+		}
+		s.mu.Unlock()
+		select {
+		case <-s.writable:
+		case <-cancel:
+			return ErrCanceled
+		}
+	}
+	defer s.mu.Unlock()
+
+	if s.wOff > 0 && s.wOff+size > s.opts.SegmentSize {
+		if err := s.nextSegment(); err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, size)
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(record)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(record))
+	copy(buf[headerSize:], record)
+	if _, err := s.w.Write(buf); err != nil {
+		return err
+	}
+	s.wOff += size
+	s.pending += size
+	signal(s.readable)
+	return nil
+}
+
+// nextSegment starts a new segment.
+func (s *Spool) nextSegment() error {
+	f, err := os.OpenFile(s.segmentPath(s.wSeg+1), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if s.r != s.w {
+		s.w.Close()
+	}
+	s.w = f
+	s.wSeg++
+	s.wOff = 0
+	return nil
+}
+
+// Read returns the next record, waiting for one to be written unless cancel
+// is closed. It returns io.EOF once the records written before CloseWrite
+// were read.
+func (s *Spool) Read(cancel <-chan struct{}) ([]byte, error) {
+	for {
+		s.mu.Lock()
+		record, err := s.next()
+		closed := s.closed
+		s.mu.Unlock()
+		if record != nil || err != nil {
+			return record, err
+		}
+		if closed {
+			return nil, io.EOF
+		}
+		select {
+		case <-s.readable:
+		case <-cancel:
+			return nil, ErrCanceled
+		}
+	}
+}
+
+// next returns the next record, or nil if all were read.
+func (s *Spool) next() ([]byte, error) {
+	for {
+		if s.rSeg == s.wSeg && s.rOff >= s.wOff {
+			return nil, nil
+		}
+		if s.r == nil {
+			if s.rSeg == s.wSeg {
+				s.r = s.w
+			} else {
+				f, err := os.Open(s.segmentPath(s.rSeg))
+				if os.IsNotExist(err) {
+					s.rSeg, s.rOff = s.rSeg+1, 0
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				s.r = f
+			}
+		}
+		record, n, err := readRecord(s.r, s.rOff)
+		if err == io.EOF || (err == errCorrupt && s.rSeg < s.wSeg) {
+			// The end of a previous segment: its last record may have been
+			// partly written before a crash.
+			s.closeReader()
+			s.rSeg, s.rOff = s.rSeg+1, 0
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spool: unable to read segment %d: %s", s.rSeg, err)
+		}
+		s.lastSeg, s.lastOff, s.last = s.rSeg, s.rOff, n
+		s.rOff += n
+		s.uncommitted += n
+		return record, nil
+	}
+}
+
+func (s *Spool) closeReader() {
+	if s.r != nil && s.r != s.w {
+		s.r.Close()
+	}
+	s.r = nil
+}
+
+// UnreadRecord undoes the last Read, which returns the same record again. It
+// can only be called once after a Read, and not after Commit.
+func (s *Spool) UnreadRecord() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == 0 {
+		return errors.New("spool: no record to unread")
+	}
+	if s.rSeg != s.lastSeg {
+		s.closeReader()
+	}
+	s.rSeg, s.rOff = s.lastSeg, s.lastOff
+	s.uncommitted -= s.last
+	s.last = 0
+	return nil
+}
+
+// Commit records that the records read so far were processed: they are not
+// read again when the spool is opened again, and they no longer count
+// towards MaxSize.
+func (s *Spool) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rSeg == s.cSeg && s.rOff == s.cOff {
+		return nil
+	}
+	if err := s.saveCursor(cursor{Segment: s.rSeg, Offset: s.rOff}); err != nil {
+		return err
+	}
+	for seg := s.cSeg; seg < s.rSeg; seg++ {
+		os.Remove(s.segmentPath(seg))
+	}
+	s.cSeg, s.cOff = s.rSeg, s.rOff
+	s.pending -= s.uncommitted
+	s.uncommitted = 0
+	s.last = 0
+	signal(s.writable)
+	return nil
+}
+
+// Pending returns the number of bytes of uncommitted records.
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Buffered returns the number of bytes of records that were not read yet.
+func (s *Spool) Buffered() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending - s.uncommitted
+}
+
+// CloseWrite tells that no more records will be written: Read returns io.EOF
+// once the others were read.
+func (s *Spool) CloseWrite() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	signal(s.readable)
+	signal(s.writable)
+}
+
+// Close closes the segment files. Uncommitted records are kept for the next
+// Open.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	signal(s.readable)
+	signal(s.writable)
+	s.closeReader()
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	s.w = nil
+	return err
+}
+
+// segments returns the numbers of the segment files, in order.
+func (s *Spool) segments() ([]int64, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []int64
+	for _, fi := range infos {
+		name := fi.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seg, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func (s *Spool) segmentPath(seg int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seg, segmentExt))
+}
+
+func (s *Spool) loadCursor() (cursor, error) {
+	var c cursor
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, cursorName))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("spool: invalid cursor: %s", err)
+	}
+	return c, nil
+}
+
+// saveCursor replaces the cursor file, syncing the new one before.
+func (s *Spool) saveCursor(c cursor) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, cursorName+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, cursorName))
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
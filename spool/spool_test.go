@@ -0,0 +1,143 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package spool
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spool-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func mustRead(t *testing.T, s *Spool, want string) {
+	t.Helper()
+	record, err := s.Read(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record) != want {
+		t.Fatalf("expected %q, got %q", want, record)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{MaxSize: 1000, SegmentSize: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := s.Write([]byte(fmt.Sprintf("record %d", i)), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		mustRead(t, s, fmt.Sprintf("record %d", i))
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	mustRead(t, s, "record 4")
+	if err := s.UnreadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	mustRead(t, s, "record 4")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Committed segments are removed, uncommitted records are read again.
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segments) != 7 {
+		t.Errorf("expected 7 segments left, got %d", len(segments))
+	}
+	s, err = Open(dir, Options{MaxSize: 1000, SegmentSize: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 4; i < 10; i++ {
+		mustRead(t, s, fmt.Sprintf("record %d", i))
+	}
+	s.CloseWrite()
+	if _, err := s.Read(nil); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestSpoolTornWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("complete"), nil)
+	s.Write([]byte("torn"), nil)
+	s.Close()
+
+	// Cut the last record, as a crash while writing would.
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	fi, _ := os.Stat(segments[0])
+	if err := os.Truncate(segments[0], fi.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Write([]byte("next"), nil)
+	mustRead(t, s, "complete")
+	mustRead(t, s, "next")
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{MaxSize: 2 * (headerSize + 5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Write([]byte("one.."), nil)
+	s.Write([]byte("two.."), nil)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := s.Write([]byte("three"), cancel); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled on a full spool, got %v", err)
+	}
+
+	written := make(chan error)
+	go func() { written <- s.Write([]byte("three"), nil) }()
+	mustRead(t, s, "one..")
+	select {
+	case <-written:
+		t.Fatal("expected the write to wait for a commit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.Commit()
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Pending(); n != 2*(headerSize+5) {
+		t.Errorf("expected 2 records pending, got %d bytes", n)
+	}
+}
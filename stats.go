@@ -0,0 +1,59 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"os"
+	"time"
+)
+
+// Stats holds the counters and the state of a Tail, see Tail.Stats.
+type Stats struct {
+	Lines       int64 // Lines delivered, counting each part of split lines
+	Bytes       int64 // Bytes of the lines delivered, line endings included
+	Rotations   int64 // Times the file was reopened after being moved, deleted or replaced
+	Truncations int64 // Times the file was reopened after being truncated
+	Switches    int64 // Times another file was followed, see TailLatest, FollowSymlinks and FilenameTemplate
+	Reopens     int64 // Times the file was reopened, for any of these reasons
+
+	RateLimitDrops int64 // Times the rate limit was reached, skipping to the end of the file
+	DroppedBytes   int64 // Bytes skipped when the rate limit was reached
+	SkippedBytes   int64 // Bytes skipped by IgnoreOlderThan and MaxBacklogBytes
+
+	File   string // The file being read, which changes with TailLatest and FilenameTemplate
+	Offset int64  // Offset following the last line delivered
+	Size   int64  // Size of the file, zero for URLs and pipes
+	Lag    int64  // Bytes left to read: Size minus Offset, if positive
+
+	LastLine      time.Time     // When the last line was delivered
+	SinceLastLine time.Duration // Time since LastLine, zero before the first line
+	Waiting       bool          // Whether the tail waits for the file to appear
+	Inactive      bool          // Whether the file was closed by CloseInactive
+}
+
+// Stats returns the counters and the state of the tail. It can be called
+// from any goroutine.
+func (tail *Tail) Stats() Stats {
+	tail.statsLk.Lock()
+	s := tail.stats
+	tail.statsLk.Unlock()
+
+	if !s.LastLine.IsZero() {
+		s.SinceLastLine = time.Since(s.LastLine)
+	}
+	if tail.remote == nil && !tail.Pipe {
+		if fi, err := os.Stat(s.File); err == nil {
+			s.Size = fi.Size()
+			if s.Size > s.Offset {
+				s.Lag = s.Size - s.Offset
+			}
+		}
+	}
+	return s
+}
+
+func (tail *Tail) updateStats(update func(s *Stats)) {
+	tail.statsLk.Lock()
+	update(&tail.stats)
+	tail.statsLk.Unlock()
+}
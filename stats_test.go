@@ -0,0 +1,92 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"testing"
+	"time"
+)
+
+// waitForStats waits until cond holds for the stats of tail.
+func waitForStats(t *testing.T, tail *Tail, cond func(s Stats) bool) Stats {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := tail.Stats()
+		if cond(s) {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats %+v", s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStats(t *testing.T) {
+	tailTest, cleanup := NewTailTest("stats", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	<-tail.Lines
+	s := waitForStats(t, tail, func(s Stats) bool { return s.Lines == 1 })
+	if s.Bytes != 6 || s.Offset != 6 || s.Size != 18 || s.Lag != 12 || s.LastLine.IsZero() {
+		t.Errorf("unexpected stats after one line %+v", s)
+	}
+
+	<-tail.Lines
+	<-tail.Lines
+	s = waitForStats(t, tail, func(s Stats) bool { return s.Lines == 3 })
+	if s.Bytes != 18 || s.Lag != 0 || s.Waiting {
+		t.Errorf("unexpected stats after all lines %+v", s)
+	}
+
+	<-time.After(100 * time.Millisecond)
+	tailTest.TruncateFile("test.txt", "a\n")
+	if line := <-tail.Lines; line.Text != "a" {
+		t.Errorf("expected a, got %s", line.Text)
+	}
+	s = waitForStats(t, tail, func(s Stats) bool { return s.Lines == 4 })
+	if s.Truncations != 1 || s.Reopens != 1 || s.Rotations != 0 || s.Offset != 2 {
+		t.Errorf("unexpected stats after truncation %+v", s)
+	}
+}
+
+func TestStatsWaiting(t *testing.T) {
+	tailTest, cleanup := NewTailTest("stats-waiting", t)
+	defer cleanup()
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, ReOpen: true, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	waitForStats(t, tail, func(s Stats) bool { return s.Waiting })
+	tailTest.CreateFile("test.txt", "hello\n")
+	<-tail.Lines
+	waitForStats(t, tail, func(s Stats) bool { return !s.Waiting && s.Lines == 1 })
+}
+
+func TestStatsRotation(t *testing.T) {
+	tailTest, cleanup := NewTailTest("stats-rotation", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\n")
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, ReOpen: true, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	<-tail.Lines
+	waitForStats(t, tail, func(s Stats) bool { return s.Lines == 1 })
+	// Like in reOpen, give the tail time to watch for each change.
+	<-time.After(100 * time.Millisecond)
+	tailTest.RenameFile("test.txt", "test.txt.1")
+	<-time.After(100 * time.Millisecond)
+	tailTest.CreateFile("test.txt", "world\n")
+	if line := <-tail.Lines; line.Text != "world" {
+		t.Errorf("expected world, got %s", line.Text)
+	}
+	s := waitForStats(t, tail, func(s Stats) bool { return s.Lines == 2 })
+	if s.Rotations != 1 || s.Reopens != 1 || s.Truncations != 0 {
+		t.Errorf("unexpected stats after rotation %+v", s)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"errors"
+	"sync"
+)
+
+// SlowConsumerPolicy tells what happens to the lines of a Subscription whose
+// buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// Block waits for the subscriber, holding back the other subscribers and
+	// the tail.
+	Block SlowConsumerPolicy = iota
+	// DropOldest drops the oldest line of the buffer to make room.
+	DropOldest
+	// Disconnect ends the Subscription with ErrSlowConsumer.
+	Disconnect
+)
+
+// ErrSlowConsumer is the Err of a Subscription ended by Disconnect.
+var ErrSlowConsumer = errors.New("subscriber too slow, disconnected")
+
+// Subscription is one of the consumers of the lines of a tail, see
+// Tail.Subscribe.
+type Subscription struct {
+	Lines <-chan *Line // A consumable channel of *Line, closed when the subscription ends
+
+	tail   *Tail
+	policy SlowConsumerPolicy
+	lines  chan *Line
+
+	mu      sync.Mutex // Held while sending
+	closed  bool
+	dropped int64
+	err     error
+
+	done     chan struct{} // Unsubscribed
+	doneOnce sync.Once
+}
+
+// fanOut holds the subscriptions of a tail.
+type fanOut struct {
+	lk      sync.Mutex
+	subs    []*Subscription
+	ring    []*Line // The last ReplayLines lines
+	next    int     // Index of the next line in ring
+	stopped bool
+}
+
+// Subscribe adds a consumer of the lines of the tail, with its own buffer
+// of bufferSize lines (at least one) and the policy applied when the buffer
+// is full. The subscriber first receives the last ReplayLines lines, as many
+// as the buffer holds.
+//
+// Once Subscribe was called, the lines are read from Tail.Lines for the
+// subscribers: Tail.Lines must not be read anymore.
+func (tail *Tail) Subscribe(bufferSize int, policy SlowConsumerPolicy) *Subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	lines := make(chan *Line, bufferSize)
+	s := &Subscription{
+		Lines:  lines,
+		tail:   tail,
+		policy: policy,
+		lines:  lines,
+		done:   make(chan struct{}),
+	}
+
+	tail.subsLk.Lock()
+	if tail.fanOut == nil {
+		tail.fanOut = &fanOut{}
+		if tail.ReplayLines > 0 {
+			tail.fanOut.ring = make([]*Line, 0, tail.ReplayLines)
+		}
+		go tail.dispatch(tail.fanOut)
+	}
+	f := tail.fanOut
+	tail.subsLk.Unlock()
+
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	replay := f.replay()
+	if len(replay) > bufferSize {
+		replay = replay[len(replay)-bufferSize:]
+	}
+	for _, line := range replay {
+		lines <- line
+	}
+	if f.stopped {
+		s.close(nil)
+	} else {
+		f.subs = append(f.subs, s)
+	}
+	return s
+}
+
+// Unsubscribe ends the subscription: Lines is closed, the lines it holds are
+// dropped.
+func (s *Subscription) Unsubscribe() {
+	s.doneOnce.Do(func() { close(s.done) })
+	s.mu.Lock()
+	s.close(nil)
+	s.mu.Unlock()
+	s.tail.unsubscribe(s)
+}
+
+// Dropped returns the number of lines dropped by DropOldest.
+func (s *Subscription) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Err returns ErrSlowConsumer if the subscription was ended by Disconnect.
+// The errors of the tail are returned by Tail.Err.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// close closes Lines, with s.mu held.
+func (s *Subscription) close(err error) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	s.drain()
+	close(s.lines)
+}
+
+// drain drops the lines left when the subscriber left.
+func (s *Subscription) drain() {
+	select {
+	case <-s.done:
+	default:
+		return
+	}
+	for {
+		select {
+		case <-s.lines:
+		default:
+			return
+		}
+	}
+}
+
+// send sends a line to the subscriber, applying the policy.
+func (s *Subscription) send(line *Line) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case DropOldest:
+		for {
+			select {
+			case s.lines <- line:
+				return
+			default:
+			}
+			select {
+			case <-s.lines:
+				s.dropped++
+			default:
+			}
+		}
+	case Disconnect:
+		select {
+		case s.lines <- line:
+		default:
+			s.close(ErrSlowConsumer)
+			s.tail.unsubscribe(s)
+		}
+	default:
+		select {
+		case s.lines <- line:
+		case <-s.done:
+		}
+	}
+}
+
+func (tail *Tail) unsubscribe(s *Subscription) {
+	f := tail.fanOut
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	for i, sub := range f.subs {
+		if sub == s {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch reads the lines of the tail and sends them to the subscribers.
+func (tail *Tail) dispatch(f *fanOut) {
+	for line := range tail.Lines {
+		f.lk.Lock()
+		f.remember(line)
+		subs := append([]*Subscription(nil), f.subs...)
+		f.lk.Unlock()
+		for _, s := range subs {
+			s.send(line)
+		}
+	}
+
+	f.lk.Lock()
+	f.stopped = true
+	subs := f.subs
+	f.subs = nil
+	f.lk.Unlock()
+	for _, s := range subs {
+		s.mu.Lock()
+		s.close(nil)
+		s.mu.Unlock()
+	}
+}
+
+// remember adds a line to the ring.
+func (f *fanOut) remember(line *Line) {
+	if cap(f.ring) == 0 {
+		return
+	}
+	if len(f.ring) < cap(f.ring) {
+		f.ring = append(f.ring, line)
+		return
+	}
+	f.ring[f.next] = line
+	f.next = (f.next + 1) % len(f.ring)
+}
+
+// replay returns the lines of the ring, oldest first.
+func (f *fanOut) replay() []*Line {
+	return append(append([]*Line(nil), f.ring[f.next:]...), f.ring[:f.next]...)
+}
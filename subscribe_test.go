@@ -0,0 +1,100 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"testing"
+	"time"
+)
+
+func readSubscription(s *Subscription) []string {
+	var texts []string
+	for line := range s.Lines {
+		texts = append(texts, line.Text)
+	}
+	return texts
+}
+
+func TestSubscribe(t *testing.T) {
+	tailTest, cleanup := NewTailTest("subscribe", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\n")
+	tail := tailTest.StartTail("test.txt", Config{ReplayLines: 2, Logger: DiscardingLogger})
+
+	a := tail.Subscribe(10, Block)
+	b := tail.Subscribe(1, Block)
+	done := make(chan []string)
+	go func() { done <- readSubscription(a) }()
+	textsB := readSubscription(b)
+	textsA := <-done
+
+	for _, texts := range [][]string{textsA, textsB} {
+		if len(texts) != 2 || texts[0] != "hello" || texts[1] != "world" {
+			t.Errorf("unexpected lines %q", texts)
+		}
+	}
+	if err := tail.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscribeSlowConsumers(t *testing.T) {
+	tailTest, cleanup := NewTailTest("subscribe-slow", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "1\n2\n3\n4\n")
+	// Subscribers that miss lines while subscribing get them from the ring.
+	tail := tailTest.StartTail("test.txt", Config{ReplayLines: 4, Logger: DiscardingLogger})
+
+	drop := tail.Subscribe(2, DropOldest)
+	disconnect := tail.Subscribe(2, Disconnect)
+	all := tail.Subscribe(10, Block)
+	if texts := readSubscription(all); len(texts) != 4 {
+		t.Fatalf("expected 4 lines, got %q", texts)
+	}
+
+	if texts := readSubscription(drop); len(texts) != 2 || texts[0] != "3" || texts[1] != "4" {
+		t.Errorf("expected the last 2 lines, got %q", texts)
+	}
+	if n := drop.Dropped(); n != 2 {
+		t.Errorf("expected 2 dropped lines, got %d", n)
+	}
+	if texts := readSubscription(disconnect); len(texts) != 2 || texts[0] != "1" {
+		t.Errorf("expected the first 2 lines, got %q", texts)
+	}
+	if err := disconnect.Err(); err != ErrSlowConsumer {
+		t.Errorf("expected ErrSlowConsumer, got %v", err)
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	tailTest, cleanup := NewTailTest("subscribe-replay", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "1\n2\n3\n")
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, ReplayLines: 2, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	a := tail.Subscribe(10, Block)
+	for i := 0; i < 3; i++ {
+		<-a.Lines
+	}
+	b := tail.Subscribe(10, Block)
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("test.txt", "4\n")
+
+	var texts []string
+	for i := 0; i < 3; i++ {
+		texts = append(texts, (<-b.Lines).Text)
+	}
+	if texts[0] != "2" || texts[1] != "3" || texts[2] != "4" {
+		t.Errorf("expected the last 2 lines then the new one, got %q", texts)
+	}
+	if line := <-a.Lines; line.Text != "4" {
+		t.Errorf("expected 4, got %s", line.Text)
+	}
+
+	a.Unsubscribe()
+	if _, ok := <-a.Lines; ok {
+		t.Error("expected Lines to be closed")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolve returns the file Filename names once its symlinks are resolved,
+// making sure it is within SymlinkRoot.
+func (tail *Tail) resolve() (string, error) {
+	target, err := filepath.EvalSymlinks(tail.name)
+	if err != nil {
+		return "", err
+	}
+	if tail.SymlinkRoot == "" {
+		return target, nil
+	}
+	if target, err = filepath.Abs(target); err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(tail.SymlinkRoot)
+	if err == nil {
+		root, err = filepath.Abs(root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to resolve %s: %s", tail.SymlinkRoot, err)
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("Unable to follow %s to %s, outside of %s", tail.name, target, tail.SymlinkRoot)
+	}
+	return target, nil
+}
+
+// checkLink looks for the symlink Filename pointing to another file, to
+// switch to it once the current one is read to its end.
+func (tail *Tail) checkLink() error {
+	target, err := tail.resolve()
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Being replaced, or removed.
+			return nil
+		}
+		return err
+	}
+	if target != tail.target {
+		tail.next = target
+	}
+	return nil
+}
+
+// closeEntry stops watching the entry of the symlink.
+func (tail *Tail) closeEntry() {
+	if tail.entry != nil {
+		tail.entry.Close()
+		tail.entry = nil
+	}
+}
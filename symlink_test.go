@@ -0,0 +1,129 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollowSymlinks(t *testing.T) {
+	testFollowSymlinks(t, "follow-symlinks", false)
+}
+
+func TestFollowSymlinksPolling(t *testing.T) {
+	testFollowSymlinks(t, "follow-symlinks-polling", true)
+}
+
+func testFollowSymlinks(t *testing.T, name string, poll bool) {
+	tailTest, cleanup := NewTailTest(name, t)
+	defer cleanup()
+	tailTest.CreateFile("app.1.log", "a\n")
+	if err := os.Symlink("app.1.log", tailTest.path+"/current"); err != nil {
+		t.Fatal(err)
+	}
+	tail := tailTest.StartTail("current", Config{
+		Follow:         true,
+		Poll:           poll,
+		PollInterval:   100 * time.Millisecond,
+		FollowSymlinks: true,
+		Logger:         DiscardingLogger,
+	})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	next := func() string {
+		select {
+		case line := <-tail.Lines:
+			return line.Text
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a line")
+			return ""
+		}
+	}
+	if text := next(); text != "a" {
+		t.Fatalf("expected a, got %s", text)
+	}
+
+	// The link is repointed atomically, after the old target was appended.
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("app.1.log", "b\n")
+	tailTest.CreateFile("app.2.log", "c\n")
+	if err := os.Symlink("app.2.log", tailTest.path+"/current.tmp"); err != nil {
+		t.Fatal(err)
+	}
+	tailTest.RenameFile("current.tmp", "current")
+	for _, expected := range []string{"b", "c"} {
+		if text := next(); text != expected {
+			t.Fatalf("expected %s, got %s", expected, text)
+		}
+	}
+	waitForStats(t, tail, func(s Stats) bool { return s.Switches == 1 })
+
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("app.2.log", "d\n")
+	if text := next(); text != "d" {
+		t.Errorf("expected d, got %s", text)
+	}
+}
+
+func TestFollowSymlinksRemoveTarget(t *testing.T) {
+	tailTest, cleanup := NewTailTest("follow-symlinks-remove-target", t)
+	defer cleanup()
+	tailTest.CreateFile("app.1.log", "a\n")
+	if err := os.Symlink("app.1.log", tailTest.path+"/current"); err != nil {
+		t.Fatal(err)
+	}
+	tail := tailTest.StartTail("current", Config{Follow: true, FollowSymlinks: true, Logger: DiscardingLogger})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	if line := <-tail.Lines; line.Text != "a" {
+		t.Fatalf("expected a, got %s", line.Text)
+	}
+
+	// Without ReOpen, removing the old target switches to the new one.
+	<-time.After(100 * time.Millisecond)
+	tailTest.CreateFile("app.2.log", "b\n")
+	if err := os.Symlink("app.2.log", tailTest.path+"/current.tmp"); err != nil {
+		t.Fatal(err)
+	}
+	tailTest.RenameFile("current.tmp", "current")
+	tailTest.RemoveFile("app.1.log")
+	select {
+	case line, ok := <-tail.Lines:
+		if !ok || line.Text != "b" {
+			t.Fatalf("expected b, got %v (%v)", line, tail.Err())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the line of the new target")
+	}
+}
+
+func TestSymlinkRoot(t *testing.T) {
+	tailTest, cleanup := NewTailTest("symlink-root", t)
+	defer cleanup()
+	if err := os.Mkdir(tailTest.path+"/root", 0755); err != nil {
+		t.Fatal(err)
+	}
+	tailTest.CreateFile("root/inside.log", "a\n")
+	tailTest.CreateFile("outside.log", "b\n")
+	for link, target := range map[string]string{"root/in": "inside.log", "root/out": "../outside.log"} {
+		if err := os.Symlink(target, tailTest.path+"/"+link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := Config{MustExist: true, SymlinkRoot: tailTest.path + "/root", Logger: DiscardingLogger}
+
+	tail, err := TailFile(tailTest.path+"/root/in", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := readAll(tail); len(lines) != 1 || lines[0].Text != "a" {
+		t.Errorf("expected the line of the file inside the root, got %d lines", len(lines))
+	}
+	if _, err := TailFile(tailTest.path+"/root/out", config); err == nil {
+		t.Error("expected an error for a symlink outside of the root")
+	}
+}
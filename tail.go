@@ -16,12 +16,14 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/nxadm/tail/ratelimiter"
+	"github.com/nxadm/tail/spool"
 	"github.com/nxadm/tail/util"
 	"github.com/nxadm/tail/watch"
 	"gopkg.in/tomb.v1"
@@ -35,7 +37,23 @@ type Line struct {
 	Num      int       // The line number
 	SeekInfo SeekInfo  // SeekInfo
 	Time     time.Time // Present time
-	Err      error     // Error from tail
+	Err      error     // Error from tail, or a *ParseError
+
+	// EventTime is the time of the logged event, found by
+	// Config.TimestampExtractor. Zero without an extractor.
+	EventTime time.Time
+
+	// Fields holds the fields parsed by Config.Parser. Lines split by
+	// MaxLineSize are parsed whole, the fields are set on their last part.
+	// It is nil without a Parser, for partial lines and when parsing failed.
+	Fields map[string]interface{}
+
+	// Partial is set when the text continues in the next Line: the line was
+	// split by MaxLineSize, or it was incomplete at the end of a followed
+	// file without CompleteLines.
+	Partial bool
+
+	ack *ackEntry // See Ack
 }
 
 // Deprecated: this function is no longer used internally and it has little of no
@@ -44,7 +62,23 @@ type Line struct {
 //
 // NewLine returns a * pointer to a Line struct.
 func NewLine(text string, lineNum int) *Line {
-	return &Line{text, lineNum, SeekInfo{}, time.Now(), nil}
+	return &Line{Text: text, Num: lineNum, Time: time.Now()}
+}
+
+// Parser turns the text of a line into named fields, see Config.Parser. The
+// parse package provides parsers for common log formats.
+type Parser interface {
+	Parse(text string) (map[string]interface{}, error)
+}
+
+// ParseError is the Err of the lines that Config.Parser failed to parse. The
+// lines are delivered anyway.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "unable to parse line: " + e.Err.Error()
 }
 
 // SeekInfo represents arguments to io.Seek. See: https://golang.org/pkg/io/#SectionReader.Seek
@@ -74,11 +108,95 @@ type Config struct {
 	Poll      bool      // Poll for file changes instead of using the default inotify
 	Pipe      bool      // The file is a named pipe (mkfifo)
 
+	// PollInterval is the time between polls: checks for changes with Poll,
+	// checks for replaced files with MaxUnchangedStats and requests for new
+	// data with TailURL. If zero, 250ms is used with Poll and one second
+	// otherwise.
+	PollInterval time.Duration
+	// MaxUnchangedStats makes ReOpen check whether Filename now names another
+	// file after so many intervals without changes, as renames can go unnoticed
+	// by inotify (tail --max-unchanged-stats). If zero, this is not checked.
+	MaxUnchangedStats int
+	// CloseInactive closes the file once it was unchanged for so long, to
+	// reopen it when it is modified, at the same offset if Filename still
+	// names it (see ReOpen otherwise). If zero, the file is kept open.
+	CloseInactive time.Duration
+	// FileBudget optionally limits the files open at once by the tails
+	// sharing it, see NewFileBudget.
+	FileBudget *FileBudget
+	// IgnoreOlderThan makes files last modified longer ago be read from their
+	// end, and MaxBacklogBytes makes files with more bytes left to read be
+	// read from their first line within that many bytes of the end. They
+	// apply whenever the file is opened, and the skipped bytes are reported
+	// by a Line with a *SkipError.
+	IgnoreOlderThan time.Duration
+	MaxBacklogBytes int64
+
 	// Generic IO
 	Follow        bool // Continue looking for new lines (tail -f)
 	MaxLineSize   int  // If non-zero, split longer lines into multiple lines
 	CompleteLines bool // Only return complete lines (that end with "\n" or EOF when Follow is false)
 
+	// URL-specific (see TailURL)
+	HTTPClient *http.Client // Client used for the requests. If nil, http.DefaultClient is used
+
+	// Optionally, parse the text of complete lines into Line.Fields
+	Parser Parser
+
+	// Optionally, set Line.EventTime to the timestamp found in lines (e.g. by
+	// NewTimestampExtractor). TimestampFallback sets it for lines without.
+	TimestampExtractor TimestampExtractor
+	TimestampFallback  TimestampFallback
+
+	// Optionally, deliver lines to be acknowledged with Line.Ack, committing
+	// the position following the acknowledged lines (see Tail.Committed). With
+	// a Checkpointer, TailFile resumes from the committed position of the
+	// file, unless Location is set, so that lines which were not acknowledged
+	// are read again.
+	Ack          bool
+	Checkpointer Checkpointer
+
+	// Optionally, with Ack and a Checkpointer, catch up from the rotated
+	// siblings of the file (e.g. NumericRotation or DateExtRotation) when the
+	// checkpoint is for a file that was rotated: the lines after the
+	// checkpoint, then those of the newer siblings, are delivered before the
+	// ones of Filename.
+	Rotation RotationScheme
+
+	// Optionally, when Filename is a symlink, follow it when it is repointed:
+	// the old target is read to its end, then the new one. The link is
+	// watched in its directory, or checked every PollInterval (1 second if
+	// zero) with Poll.
+	FollowSymlinks bool
+
+	// Optionally, refuse to read Filename if, with symlinks resolved, it is
+	// not within the directory SymlinkRoot.
+	SymlinkRoot string
+
+	// Optionally, have TailFile take the filename as a strftime-style template
+	// (see ExpandTemplate), like /var/log/app/%Y-%m-%d.log, naming the file
+	// of the current time in TemplateTimeZone (time.Local if nil). The name
+	// is computed again every TemplateInterval (1 minute if zero) and at
+	// midnight. Once the new file appears, the old one is read to its end,
+	// then the new one from the beginning. Filename is the file read first,
+	// see TailLatest.
+	FilenameTemplate bool
+	TemplateTimeZone *time.Location
+	TemplateInterval time.Duration
+
+	// Number of lines kept to prime new subscribers, see Tail.Subscribe.
+	ReplayLines int
+
+	// Optionally, have TailFile read the file at full speed into a spool of
+	// segment files in SpoolDir, holding up to SpoolSize bytes (64MB if zero),
+	// from which Lines is fed at the pace of the consumer. Lines left in the
+	// spool when the tail stops are delivered first by the next tail of
+	// SpoolDir, which resumes reading the file after the lines spooled, unless
+	// Location is set. Fields are restored as decoded from JSON, and the Err
+	// of lines is only kept as text. SpoolDir cannot be combined with Ack.
+	SpoolDir  string
+	SpoolSize int64
+
 	// Optionally, use a ratelimiter (e.g. created by the ratelimiter/NewLeakyBucket function)
 	RateLimiter *ratelimiter.LeakyBucket
 
@@ -88,7 +206,7 @@ type Config struct {
 }
 
 type Tail struct {
-	Filename string     // The filename
+	Filename string     // The filename, the first one read if it is switched
 	Lines    chan *Line // A consumable channel of *Line
 	Config              // Tail.Configuration
 
@@ -98,12 +216,43 @@ type Tail struct {
 
 	lineBuf *strings.Builder
 
-	watcher watch.FileWatcher
-	changes *watch.FileChanges
+	eventTime time.Time // EventTime of the last line
+
+	acker *acker // With Ack
+
+	fanOut *fanOut // Once Subscribe was called
+	subsLk sync.Mutex
+
+	spool     *spool.Spool // With SpoolDir
+	spoolDone chan struct{}
+	spooled   *spoolSource
+
+	watcher   watch.FileWatcher
+	changes   *watch.FileChanges
+	unchanged int // Intervals without changes, see MaxUnchangedStats
+
+	inactive *inactiveFile // The file closed by CloseInactive
+
+	remote *httpSource
+
+	rotated *rotatedSource // While catching up, see Config.Rotation
+
+	name   string       // The file being read: Filename, or the one switched to
+	latest *latestFiles // With TailLatest
+
+	target   string              // The file name links to, with FollowSymlinks
+	entry    *watch.EntryWatcher // Of the symlink, with FollowSymlinks
+	next     string              // The file to switch to at the end of the current one
+	checkAt  time.Time           // Of the next check for a file to switch to
+	template string              // Filename template, with FilenameTemplate
+	watching *tomb.Tomb          // Of the watch of the current file, when switching
 
 	tomb.Tomb // provides: Done, Kill, Dying
 
 	lk sync.Mutex
+
+	stats   Stats
+	statsLk sync.Mutex
 }
 
 var (
@@ -119,39 +268,60 @@ var (
 // after finishing reading from the Lines channel, invoke the `Wait` or `Err`
 // method on the returned *Tail.
 func TailFile(filename string, config Config) (*Tail, error) {
+	return tailFile(filename, config, nil)
+}
+
+func tailFile(filename string, config Config, latest *latestFiles) (*Tail, error) {
 	if config.ReOpen && !config.Follow {
 		util.Fatal("cannot set ReOpen without Follow.")
 	}
 
+	var template string
+	if config.FilenameTemplate {
+		template = filename
+		filename = templateName(template, config.TemplateTimeZone)
+	}
+
 	t := &Tail{
 		Filename: filename,
+		name:     filename,
 		Lines:    make(chan *Line),
 		Config:   config,
+		latest:   latest,
+		template: template,
 	}
+	t.stats.File = filename
 
 	if config.CompleteLines {
 		t.lineBuf = new(strings.Builder)
 	}
+	if config.Ack {
+		t.acker = newAcker(t)
+	}
 
 	// when Logger was not specified in config, use default logger
 	if t.Logger == nil {
 		t.Logger = DefaultLogger
 	}
 
-	if t.Poll {
-		t.watcher = watch.NewPollingFileWatcher(filename)
-	} else {
-		t.watcher = watch.NewInotifyFileWatcher(filename)
-	}
+	t.watcher = t.newWatcher(filename)
 
 	if t.MustExist {
 		var err error
-		t.file, err = OpenFile(t.Filename)
+		t.file, err = t.openFile()
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if t.SpoolDir != "" {
+		if err := t.openSpool(); err != nil {
+			t.closeFile()
+			return nil, err
+		}
+		go t.drainSpool()
+	}
+
 	go t.tailFileSync()
 
 	return t, nil
@@ -161,10 +331,22 @@ func TailFile(filename string, config Config) (*Tail, error) {
 // Beware that this value may not be completely accurate because one line from
 // the chan(tail.Lines) may have been read already.
 func (tail *Tail) Tell() (offset int64, err error) {
-	if tail.file == nil {
+	if tail.remote != nil {
+		return tail.remote.tell(tail), nil
+	}
+	tail.lk.Lock()
+	file, inactive, rotated := tail.file, tail.inactive, tail.rotated
+	tail.lk.Unlock()
+	if rotated != nil {
+		return rotated.tell(tail), nil
+	}
+	if file == nil {
+		if inactive != nil {
+			return inactive.offset, nil
+		}
 		return offset, err
 	}
-	offset, err = tail.file.Seek(0, io.SeekCurrent)
+	offset, err = file.Seek(0, io.SeekCurrent)
 	if err != nil {
 		return offset, err
 	}
@@ -195,14 +377,28 @@ func (tail *Tail) StopAtEOF() error {
 var errStopAtEOF = errors.New("tail: stop at eof")
 
 func (tail *Tail) close() {
-	close(tail.Lines)
+	if tail.acker != nil {
+		tail.acker.stop()
+	}
+	if tail.spool != nil {
+		tail.saveSpoolSource()
+		// drainSpool closes Lines.
+		tail.spool.CloseWrite()
+		<-tail.spoolDone
+	} else {
+		close(tail.Lines)
+	}
 	tail.closeFile()
+	tail.closeEntry()
 }
 
 func (tail *Tail) closeFile() {
 	if tail.file != nil {
 		tail.file.Close()
 		tail.file = nil
+		if tail.FileBudget != nil {
+			tail.FileBudget.release()
+		}
 	}
 }
 
@@ -211,25 +407,41 @@ func (tail *Tail) reopen() error {
 		tail.lineBuf.Reset()
 	}
 	tail.closeFile()
+	if tail.inactive != nil {
+		tail.lk.Lock()
+		tail.inactive = nil
+		tail.lk.Unlock()
+		tail.updateStats(func(s *Stats) { s.Inactive = false })
+	}
 	tail.lineNum = 0
+	// The entry of the symlink is watched again once the file is open, not to
+	// share it with BlockUntilExists.
+	tail.closeEntry()
 	for {
 		var err error
-		tail.file, err = OpenFile(tail.Filename)
+		tail.file, err = tail.openFile()
+		if err == tomb.ErrDying {
+			return err
+		}
 		if err != nil {
 			if os.IsNotExist(err) {
-				tail.Logger.Printf("Waiting for %s to appear...", tail.Filename)
-				if err := tail.watcher.BlockUntilExists(&tail.Tomb); err != nil {
+				tail.Logger.Printf("Waiting for %s to appear...", tail.name)
+				tail.updateStats(func(s *Stats) { s.Waiting = true })
+				err := tail.watcher.BlockUntilExists(&tail.Tomb)
+				tail.updateStats(func(s *Stats) { s.Waiting = false })
+				if err != nil {
 					if err == tomb.ErrDying {
 						return err
 					}
-					return fmt.Errorf("Failed to detect creation of %s: %s", tail.Filename, err)
+					return fmt.Errorf("Failed to detect creation of %s: %s", tail.name, err)
 				}
 				continue
 			}
-			return fmt.Errorf("Unable to open file %s: %s", tail.Filename, err)
+			return fmt.Errorf("Unable to open file %s: %s", tail.name, err)
 		}
 		break
 	}
+	tail.updateStats(func(s *Stats) { s.Offset = 0 })
 	return nil
 }
 
@@ -280,13 +492,33 @@ func (tail *Tail) tailFileSync() {
 		}
 	}
 
+	if tail.Location == nil && !tail.Pipe {
+		var err error
+		if tail.acker != nil && tail.Checkpointer != nil {
+			err = tail.restoreCheckpoint(tail.Checkpointer, tail.acker.key)
+		} else if tail.spool != nil {
+			err = tail.restoreCheckpoint(tail.spooled.checkpointer, tail.spooled.key)
+		}
+		if err != nil {
+			tail.Killf("Unable to restore the checkpoint of %s: %s", tail.name, err)
+			return
+		}
+	}
+
 	// Seek to requested location on first open of the file.
+	var offset int64
 	if tail.Location != nil {
-		_, err := tail.file.Seek(tail.Location.Offset, tail.Location.Whence)
+		var err error
+		offset, err = tail.file.Seek(tail.Location.Offset, tail.Location.Whence)
 		if err != nil {
-			tail.Killf("Seek error on %s: %s", tail.Filename, err)
+			tail.Killf("Seek error on %s: %s", tail.name, err)
 			return
 		}
+		tail.updateStats(func(s *Stats) { s.Offset = offset })
+	}
+	offset = tail.skipBacklog(offset)
+	if tail.acker != nil && !tail.Pipe {
+		tail.acker.opened(tail.file, offset)
 	}
 
 	tail.openReader()
@@ -307,43 +539,56 @@ func (tail *Tail) tailFileSync() {
 		// Process `line` even if err is EOF.
 		switch err {
 		case nil:
-			cooloff := !tail.sendLine(line)
+			cooloff := !tail.sendLine(line, false)
 			if cooloff {
 				// Wait a second before seeking till the end of
 				// file when rate limit is reached.
-				msg := ("Too much log activity; waiting a second before resuming tailing")
-				offset, _ := tail.Tell()
-				tail.Lines <- &Line{msg, tail.lineNum, SeekInfo{Offset: offset}, time.Now(), errors.New(msg)}
-				select {
-				case <-time.After(time.Second):
-				case <-tail.Dying():
+				if !tail.cooloff() {
 					return
 				}
+				before, _ := tail.Tell()
 				if err := tail.seekEnd(); err != nil {
 					tail.Kill(err)
 					return
 				}
+				after, _ := tail.Tell()
+				if tail.acker != nil {
+					tail.acker.skip(after)
+				}
+				tail.updateStats(func(s *Stats) {
+					s.RateLimitDrops++
+					s.DroppedBytes += after - before
+					s.Offset = after
+				})
 			}
 		case io.EOF:
 			if !tail.Follow {
 				if line != "" {
-					tail.sendLine(line)
+					tail.sendLine(line, false)
 				}
 				return
 			}
 
 			if tail.Follow && line != "" {
-				tail.sendLine(line)
+				tail.sendLine(line, true)
 				if err := tail.seekEnd(); err != nil {
 					tail.Kill(err)
 					return
 				}
 			}
 
+			if tail.spool != nil {
+				tail.saveSpoolSource()
+			}
+
 			// When EOF is reached, wait for more data to become
 			// available. Wait strategy is based on the `tail.watcher`
-			// implementation (inotify or polling).
+			// implementation (inotify or polling). A file closed by
+			// CloseInactive is waited for until it changes.
 			err := tail.waitForChanges()
+			for err == nil && tail.inactive != nil {
+				err = tail.waitForChanges()
+			}
 			if err != nil {
 				if err != ErrStop {
 					tail.Kill(err)
@@ -352,7 +597,7 @@ func (tail *Tail) tailFileSync() {
 			}
 		default:
 			// non-EOF error
-			tail.Killf("Error reading %s: %s", tail.Filename, err)
+			tail.Killf("Error reading %s: %s", tail.name, err)
 			return
 		}
 
@@ -371,41 +616,149 @@ func (tail *Tail) tailFileSync() {
 // moved or truncated. When moved or deleted - the file will be
 // reopened if ReOpen is true. Truncated files are always reopened.
 func (tail *Tail) waitForChanges() error {
+	if tail.next != "" {
+		return tail.switchFile()
+	}
 	if tail.changes == nil {
 		pos, err := tail.file.Seek(0, io.SeekCurrent)
 		if err != nil {
 			return err
 		}
-		tail.changes, err = tail.watcher.ChangeEvents(&tail.Tomb, pos)
+		w := tail.watcher
+		if tail.target != "" {
+			w = tail.newWatcher(tail.target)
+		}
+		tail.changes, err = w.ChangeEvents(tail.watchTomb(), pos)
 		if err != nil {
 			return err
 		}
 	}
+	var entryChanged <-chan bool
+	if tail.target != "" && !tail.Poll {
+		if tail.entry == nil {
+			var err error
+			if tail.entry, err = watch.WatchEntry(tail.name); err != nil {
+				return err
+			}
+		}
+		entryChanged = tail.entry.Changed
+	}
+
+	var statTimeout <-chan time.Time
+	if tail.ReOpen && tail.MaxUnchangedStats > 0 {
+		interval := tail.PollInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		statTimeout = time.After(interval)
+	}
+	var checkTimeout <-chan time.Time
+	if wait := tail.checkWait(); wait > 0 {
+		// Kept across calls, for the check to happen while the file changes.
+		if tail.checkAt.IsZero() {
+			tail.checkAt = time.Now().Add(wait)
+		}
+		checkTimeout = time.After(time.Until(tail.checkAt))
+	}
+	var inactiveTimeout <-chan time.Time
+	if tail.CloseInactive > 0 && tail.file != nil && !tail.Pipe {
+		inactiveTimeout = time.After(tail.CloseInactive)
+	}
 
 	select {
 	case <-tail.changes.Modified:
+		tail.unchanged = 0
+		if tail.inactive == nil {
+			return nil
+		}
+		ok, err := tail.wakeUp()
+		if err != nil || ok {
+			return err
+		}
+		// name no longer names the file that was closed.
+		tail.unwatch()
+		if !tail.ReOpen {
+			tail.Logger.Printf("Stopping tail as file no longer exists: %s", tail.name)
+			return ErrStop
+		}
+		tail.Logger.Printf("Re-opening replaced file %s ...", tail.name)
+		if err := tail.reopen(); err != nil {
+			return err
+		}
+		tail.updateStats(func(s *Stats) { s.Rotations++; s.Reopens++ })
+		tail.reopened()
+		tail.Logger.Printf("Successfully reopened %s", tail.name)
+		tail.openReader()
+		return nil
+	case <-checkTimeout:
+		tail.checkAt = time.Time{}
+		switch {
+		case tail.latest != nil:
+			tail.checkLatest()
+		case tail.template != "":
+			tail.checkTemplate()
+		default:
+			return tail.checkLink()
+		}
+		return nil
+	case <-entryChanged:
+		return tail.checkLink()
+	case <-inactiveTimeout:
+		return tail.closeInactive()
+	case <-statTimeout:
+		if tail.unchanged++; tail.unchanged < tail.MaxUnchangedStats {
+			return nil
+		}
+		tail.unchanged = 0
+		if !tail.replaced() {
+			return nil
+		}
+		tail.unwatch()
+		tail.Logger.Printf("Re-opening replaced file %s ...", tail.name)
+		if err := tail.reopen(); err != nil {
+			return err
+		}
+		tail.updateStats(func(s *Stats) { s.Rotations++; s.Reopens++ })
+		tail.reopened()
+		tail.Logger.Printf("Successfully reopened %s", tail.name)
+		tail.openReader()
 		return nil
 	case <-tail.changes.Deleted:
 		tail.changes = nil
+		if tail.latest != nil {
+			if tail.checkLatest(); tail.next != "" {
+				return nil
+			}
+		}
+		if tail.target != "" {
+			// The old target of a repointed symlink may be removed.
+			if err := tail.checkLink(); err != nil || tail.next != "" {
+				return err
+			}
+		}
 		if tail.ReOpen {
 			// XXX: we must not log from a library.
-			tail.Logger.Printf("Re-opening moved/deleted file %s ...", tail.Filename)
+			tail.Logger.Printf("Re-opening moved/deleted file %s ...", tail.name)
 			if err := tail.reopen(); err != nil {
 				return err
 			}
-			tail.Logger.Printf("Successfully reopened %s", tail.Filename)
+			tail.updateStats(func(s *Stats) { s.Rotations++; s.Reopens++ })
+			tail.reopened()
+			tail.Logger.Printf("Successfully reopened %s", tail.name)
 			tail.openReader()
 			return nil
 		}
-		tail.Logger.Printf("Stopping tail as file no longer exists: %s", tail.Filename)
+		tail.Logger.Printf("Stopping tail as file no longer exists: %s", tail.name)
 		return ErrStop
 	case <-tail.changes.Truncated:
 		// Always reopen truncated files (Follow is true)
-		tail.Logger.Printf("Re-opening truncated file %s ...", tail.Filename)
+		tail.Logger.Printf("Re-opening truncated file %s ...", tail.name)
 		if err := tail.reopen(); err != nil {
 			return err
 		}
-		tail.Logger.Printf("Successfully reopened truncated %s", tail.Filename)
+		tail.updateStats(func(s *Stats) { s.Truncations++; s.Reopens++ })
+		tail.reopened()
+		tail.Logger.Printf("Successfully reopened truncated %s", tail.name)
 		tail.openReader()
 		return nil
 	case <-tail.Dying():
@@ -413,13 +766,129 @@ func (tail *Tail) waitForChanges() error {
 	}
 }
 
+// reopened applies IgnoreOlderThan and MaxBacklogBytes to the file that
+// was reopened, and tells the acker.
+func (tail *Tail) reopened() {
+	offset := tail.skipBacklog(0)
+	if tail.acker != nil && !tail.Pipe {
+		tail.acker.opened(tail.file, offset)
+	}
+}
+
+// replaced reports whether name no longer names the open file.
+func (tail *Tail) replaced() bool {
+	fi, err := os.Stat(tail.name)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	if tail.inactive != nil {
+		return !os.SameFile(fi, tail.inactive.info)
+	}
+	current, err := tail.file.Stat()
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(fi, current)
+}
+
+// unwatch stops the delivery of changes of the open file, so that the next
+// call to waitForChanges watches the file opened in the meantime.
+func (tail *Tail) unwatch() {
+	if tail.changes == nil {
+		return
+	}
+	tail.changes = nil
+	if _, ok := tail.watcher.(*watch.InotifyFileWatcher); ok {
+		if tail.target != "" {
+			watch.RemoveWatch(tail.target)
+		} else {
+			watch.RemoveWatch(tail.name)
+		}
+	}
+}
+
+// checkWait returns the time between the checks for another file to read,
+// or zero if there is none to do.
+func (tail *Tail) checkWait() time.Duration {
+	if tail.template != "" {
+		return tail.templateWait()
+	}
+	if tail.latest == nil && (tail.target == "" || !tail.Poll) {
+		return 0
+	}
+	if tail.PollInterval <= 0 {
+		return time.Second
+	}
+	return tail.PollInterval
+}
+
+// switchFile reads the file found by checkLatest, checkLink or
+// checkTemplate, once the current one was read to its end.
+func (tail *Tail) switchFile() error {
+	name := tail.next
+	tail.next = ""
+	tail.unwatch()
+	if tail.watching != nil {
+		tail.watching.Kill(nil)
+		tail.watching = nil
+	}
+	if tail.latest != nil || tail.template != "" {
+		tail.Logger.Printf("Switching from %s to the newer %s ...", tail.name, name)
+		tail.name = name
+		tail.watcher = tail.newWatcher(name)
+	} else {
+		tail.Logger.Printf("Following %s to %s ...", tail.name, name)
+	}
+	if err := tail.reopen(); err != nil {
+		return err
+	}
+	tail.updateStats(func(s *Stats) { s.File = tail.name; s.Switches++; s.Reopens++ })
+	tail.reopened()
+	tail.openReader()
+	return nil
+}
+
+// watchTomb returns the tomb of the watch of the current file, which dies
+// with the tail, or on switch when the tail can switch files.
+func (tail *Tail) watchTomb() *tomb.Tomb {
+	if tail.latest == nil && tail.template == "" && !tail.FollowSymlinks {
+		return &tail.Tomb
+	}
+	if tail.watching != nil {
+		tail.watching.Kill(nil)
+	}
+	t := &tomb.Tomb{}
+	go func() {
+		select {
+		case <-tail.Dying():
+			t.Kill(nil)
+		case <-t.Dying():
+		}
+	}()
+	tail.watching = t
+	return t
+}
+
+func (tail *Tail) newWatcher(filename string) watch.FileWatcher {
+	if tail.Poll {
+		w := watch.NewPollingFileWatcher(filename)
+		w.Interval = tail.PollInterval
+		return w
+	}
+	return watch.NewInotifyFileWatcher(filename)
+}
+
 func (tail *Tail) openReader() {
+	tail.setReader(tail.file)
+}
+
+func (tail *Tail) setReader(r io.Reader) {
 	tail.lk.Lock()
 	if tail.MaxLineSize > 0 {
 		// add 2 to account for newline characters
-		tail.reader = bufio.NewReaderSize(tail.file, tail.MaxLineSize+2)
+		tail.reader = bufio.NewReaderSize(r, tail.MaxLineSize+2)
 	} else {
-		tail.reader = bufio.NewReader(tail.file)
+		tail.reader = bufio.NewReader(r)
 	}
 	tail.lk.Unlock()
 }
@@ -431,16 +900,30 @@ func (tail *Tail) seekEnd() error {
 func (tail *Tail) seekTo(pos SeekInfo) error {
 	_, err := tail.file.Seek(pos.Offset, pos.Whence)
 	if err != nil {
-		return fmt.Errorf("Seek error on %s: %s", tail.Filename, err)
+		return fmt.Errorf("Seek error on %s: %s", tail.name, err)
 	}
 	// Reset the read buffer whenever the file is re-seek'ed
 	tail.reader.Reset(tail.file)
 	return nil
 }
 
+// cooloff reports that the rate limit was reached and waits a second
+// before tailing resumes. It returns false if the tail is stopped meanwhile.
+func (tail *Tail) cooloff() bool {
+	msg := ("Too much log activity; waiting a second before resuming tailing")
+	offset, _ := tail.Tell()
+	tail.deliver(&Line{Text: msg, Num: tail.lineNum, SeekInfo: SeekInfo{Offset: offset}, Time: time.Now(), Err: errors.New(msg)})
+	select {
+	case <-time.After(time.Second):
+		return true
+	case <-tail.Dying():
+		return false
+	}
+}
+
 // sendLine sends the line(s) to Lines channel, splitting longer lines
 // if necessary. Return false if rate limit is reached.
-func (tail *Tail) sendLine(line string) bool {
+func (tail *Tail) sendLine(line string, partial bool) bool {
 	now := time.Now()
 	lines := []string{line}
 
@@ -449,21 +932,58 @@ func (tail *Tail) sendLine(line string) bool {
 		lines = util.PartitionString(line, tail.MaxLineSize)
 	}
 
-	for _, line := range lines {
+	// Parse the whole line, the fields go with its last part.
+	var fields map[string]interface{}
+	var parseErr error
+	if tail.Parser != nil && !partial {
+		if fields, parseErr = tail.Parser.Parse(line); parseErr != nil {
+			parseErr = &ParseError{Err: parseErr}
+		}
+	}
+
+	var eventTime time.Time
+	if tail.TimestampExtractor != nil {
+		eventTime = tail.extractTimestamp(line, fields, now)
+	}
+
+	for i, line := range lines {
 		tail.lineNum++
 		offset, _ := tail.Tell()
-		select {
-		case tail.Lines <- &Line{line, tail.lineNum, SeekInfo{Offset: offset}, now, nil}:
-		case <-tail.Dying():
+		l := &Line{
+			Text:      line,
+			Num:       tail.lineNum,
+			SeekInfo:  SeekInfo{Offset: offset},
+			Time:      now,
+			EventTime: eventTime,
+			Partial:   partial || i < len(lines)-1,
+		}
+		if !l.Partial {
+			l.Fields, l.Err = fields, parseErr
+		}
+		if tail.acker != nil {
+			l.ack = tail.acker.track(offset, l.Partial)
+		}
+		if !tail.deliver(l) {
 			return true
 		}
+
+		size := int64(len(line))
+		if !l.Partial {
+			size++ // Line ending
+		}
+		tail.updateStats(func(s *Stats) {
+			s.Lines++
+			s.Bytes += size
+			s.Offset = offset
+			s.LastLine = now
+		})
 	}
 
 	if tail.Config.RateLimiter != nil {
 		ok := tail.Config.RateLimiter.Pour(uint16(len(lines)))
 		if !ok {
 			tail.Logger.Printf("Leaky bucket full (%v); entering 1s cooloff period.",
-				tail.Filename)
+				tail.name)
 			return false
 		}
 	}
@@ -471,10 +991,46 @@ func (tail *Tail) sendLine(line string) bool {
 	return true
 }
 
+// deliver sends a line on Lines, or to the spool. It returns false if the
+// tail is stopped meanwhile.
+func (tail *Tail) deliver(l *Line) bool {
+	if tail.spool != nil {
+		return tail.spoolLine(l)
+	}
+	select {
+	case tail.Lines <- l:
+		return true
+	case <-tail.Dying():
+		return false
+	}
+}
+
+// extractTimestamp returns the EventTime of a line, applying the
+// TimestampFallback to lines without timestamp.
+func (tail *Tail) extractTimestamp(text string, fields map[string]interface{}, now time.Time) time.Time {
+	if t, ok := tail.TimestampExtractor.Extract(text, fields); ok {
+		tail.eventTime = t
+		return t
+	}
+	switch tail.TimestampFallback {
+	case FallbackPrevious:
+		return tail.eventTime
+	case FallbackReadTime:
+		return now
+	}
+	return time.Time{}
+}
+
 // Cleanup removes inotify watches added by the tail package. This function is
 // meant to be invoked from a process's exit handler. Linux kernel may not
 // automatically remove inotify watches after the process exits.
 // If you plan to re-read a file, don't call Cleanup in between.
 func (tail *Tail) Cleanup() {
-	watch.Cleanup(tail.Filename)
+	if tail.remote != nil {
+		return
+	}
+	watch.Cleanup(tail.name)
+	if tail.target != "" {
+		watch.Cleanup(tail.target)
+	}
 }
@@ -0,0 +1,448 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultURLPollInterval is used by TailURL when Config.PollInterval is zero.
+const defaultURLPollInterval = time.Second
+
+// overlapSize is the number of already delivered bytes that are requested
+// again on every poll, to verify that the remote file was not replaced.
+const overlapSize = 64
+
+var errRemoteNotFound = errors.New("remote file not found")
+
+// TailURL begins tailing a file served over HTTP(S). The file is polled every
+// Config.PollInterval with "Range: bytes=<offset>-" requests and the received
+// data goes through the same line handling as TailFile (MaxLineSize,
+// CompleteLines, RateLimiter).
+//
+// Truncation is detected through the total size reported by Content-Range.
+// Replacement is detected through ETag/Last-Modified and by verifying the
+// bytes preceding the offset, which are requested again on every poll.
+// A truncated or replaced file is read again from the beginning.
+//
+// ReOpen, Poll and Pipe have no meaning for remote files and are ignored.
+func TailURL(url string, config Config) (*Tail, error) {
+	t := &Tail{
+		Filename: url,
+		Lines:    make(chan *Line),
+		Config:   config,
+	}
+
+	if config.CompleteLines {
+		t.lineBuf = new(strings.Builder)
+	}
+	if config.Ack {
+		t.acker = newAcker(t)
+	}
+
+	// when Logger was not specified in config, use default logger
+	if t.Logger == nil {
+		t.Logger = DefaultLogger
+	}
+	if t.HTTPClient == nil {
+		t.HTTPClient = http.DefaultClient
+	}
+	if t.PollInterval == 0 {
+		t.PollInterval = defaultURLPollInterval
+	}
+
+	t.remote = &httpSource{url: url, client: t.HTTPClient, lk: &t.lk, total: -1}
+
+	if t.MustExist {
+		if _, err := t.remote.size(context.Background()); err != nil {
+			return nil, fmt.Errorf("Unable to open %s: %s", url, err)
+		}
+	}
+
+	go t.tailURLSync()
+
+	return t, nil
+}
+
+func (tail *Tail) tailURLSync() {
+	defer tail.Done()
+	defer tail.close()
+
+	// Abort pending requests when the tail is stopped, unless it was asked
+	// to finish reading what is available.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-tail.Dying():
+			if tail.Err() != errStopAtEOF {
+				cancel()
+			}
+		case <-ctx.Done():
+		}
+	}()
+
+	src := tail.remote
+	waiting := false
+	positioned := tail.Location == nil
+
+	for {
+		var err error
+		truncated := false
+		if !positioned {
+			err = tail.seekURL(ctx)
+			positioned = err == nil
+		} else {
+			truncated, err = src.fetch(ctx)
+		}
+
+		switch {
+		case err == errRemoteNotFound:
+			if !tail.Follow {
+				tail.Killf("Unable to open %s: %s", tail.Filename, err)
+				return
+			}
+			if !waiting {
+				tail.Logger.Printf("Waiting for %s to appear...", tail.Filename)
+				waiting = true
+			}
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			if !tail.Follow {
+				tail.Killf("Error reading %s: %s", tail.Filename, err)
+				return
+			}
+			tail.Logger.Printf("Error polling %s: %s", tail.Filename, err)
+		case truncated:
+			tail.Logger.Printf("Re-reading truncated or replaced %s ...", tail.Filename)
+			if tail.lineBuf != nil {
+				tail.lineBuf.Reset()
+			}
+			tail.lineNum = 0
+			continue
+		case src.body != nil:
+			waiting = false
+			if !tail.readURL() {
+				return
+			}
+		}
+
+		if !tail.Follow {
+			return
+		}
+
+		select {
+		case <-time.After(tail.PollInterval):
+		case <-tail.Dying():
+			return
+		}
+	}
+}
+
+// seekURL applies Config.Location to the remote file.
+func (tail *Tail) seekURL(ctx context.Context) error {
+	offset := tail.Location.Offset
+	if tail.Location.Whence == io.SeekEnd {
+		size, err := tail.remote.size(ctx)
+		if err != nil {
+			return err
+		}
+		offset += size
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	tail.lk.Lock()
+	tail.remote.offset = offset
+	tail.lk.Unlock()
+	return nil
+}
+
+// readURL sends the lines of the last fetched response body. It returns false
+// when tailing must stop.
+func (tail *Tail) readURL() bool {
+	src := tail.remote
+	defer src.closeBody()
+
+	tail.setReader(src)
+
+	for {
+		line, err := tail.readLine()
+
+		switch err {
+		case nil:
+			if !tail.sendLine(line, false) {
+				if !tail.cooloff() {
+					return false
+				}
+				tail.lk.Lock()
+				src.skipToEnd()
+				tail.reader.Reset(src)
+				tail.lk.Unlock()
+				return true
+			}
+		default:
+			if err != io.EOF {
+				select {
+				case <-tail.Dying():
+					return false
+				default:
+				}
+				tail.Logger.Printf("Error reading %s: %s", tail.Filename, err)
+			}
+			// The data read so far has been consumed, the next poll
+			// resumes right after it.
+			if line != "" {
+				tail.sendLine(line, tail.Follow)
+			}
+			return true
+		}
+
+		select {
+		case <-tail.Dying():
+			if tail.Err() == errStopAtEOF {
+				continue
+			}
+			return false
+		default:
+		}
+	}
+}
+
+// httpSource keeps track of the position in a remote file and reads the
+// response bodies for TailURL. Reads happen while holding Tail.lk, which is
+// also taken whenever the position is changed otherwise.
+type httpSource struct {
+	url    string
+	client *http.Client
+	lk     *sync.Mutex
+
+	offset       int64  // Bytes delivered to the reader
+	overlap      []byte // The last bytes before offset
+	total        int64  // Last known size of the file, -1 if unknown
+	etag         string
+	lastModified time.Time
+
+	body io.ReadCloser
+}
+
+func (src *httpSource) Read(p []byte) (int, error) {
+	if src.body == nil {
+		return 0, io.EOF
+	}
+	n, err := src.body.Read(p)
+	src.offset += int64(n)
+	src.remember(p[:n])
+	return n, err
+}
+
+// remember keeps the last overlapSize bytes that were read.
+func (src *httpSource) remember(p []byte) {
+	if len(p) >= overlapSize {
+		src.overlap = append(src.overlap[:0], p[len(p)-overlapSize:]...)
+		return
+	}
+	src.overlap = append(src.overlap, p...)
+	if extra := len(src.overlap) - overlapSize; extra > 0 {
+		src.overlap = src.overlap[:copy(src.overlap, src.overlap[extra:])]
+	}
+}
+
+func (src *httpSource) tell(tail *Tail) int64 {
+	tail.lk.Lock()
+	defer tail.lk.Unlock()
+	if tail.reader == nil {
+		return src.offset
+	}
+	return src.offset - int64(tail.reader.Buffered())
+}
+
+func (src *httpSource) reset() {
+	src.lk.Lock()
+	defer src.lk.Unlock()
+	src.offset = 0
+	src.overlap = nil
+	src.etag = ""
+	src.lastModified = time.Time{}
+}
+
+func (src *httpSource) skipToEnd() {
+	src.closeBody()
+	if src.total > src.offset {
+		src.offset = src.total
+		src.overlap = nil
+	}
+}
+
+func (src *httpSource) closeBody() {
+	if src.body != nil {
+		src.body.Close()
+		src.body = nil
+	}
+}
+
+// size returns the current size of the remote file.
+func (src *httpSource) size(ctx context.Context) (int64, error) {
+	resp, err := src.get(ctx, "bytes=0-0", false)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if resp.ContentLength < 0 {
+			return 0, errors.New("size of remote file is unknown")
+		}
+		return resp.ContentLength, nil
+	case http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		_, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, err
+		}
+		return total, nil
+	case http.StatusNotFound, http.StatusGone:
+		return 0, errRemoteNotFound
+	default:
+		return 0, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+}
+
+// fetch requests the data following the current offset and makes it
+// available through Read. When there is no new data, no body is set. When the
+// remote file was truncated or replaced, the position is reset to the start
+// of the file and truncated is true.
+func (src *httpSource) fetch(ctx context.Context) (truncated bool, err error) {
+	start := src.offset - int64(len(src.overlap))
+	resp, err := src.get(ctx, fmt.Sprintf("bytes=%d-", start), true)
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return false, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		_, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err == nil && total < src.offset {
+			src.reset()
+			return true, nil
+		}
+		return false, nil
+	case http.StatusOK:
+		// The server ignored the range, skip what was read already.
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, start); err != nil {
+			resp.Body.Close()
+			if err == io.EOF {
+				src.reset()
+				return true, nil
+			}
+			return false, err
+		}
+		src.total = resp.ContentLength
+	case http.StatusPartialContent:
+		first, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || first != start {
+			resp.Body.Close()
+			return false, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		if total >= 0 && total < src.offset {
+			resp.Body.Close()
+			src.reset()
+			return true, nil
+		}
+		src.total = total
+	case http.StatusNotFound, http.StatusGone:
+		resp.Body.Close()
+		return false, errRemoteNotFound
+	default:
+		resp.Body.Close()
+		return false, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if lastModified.Before(src.lastModified) {
+		resp.Body.Close()
+		src.reset()
+		return true, nil
+	}
+
+	if len(src.overlap) > 0 {
+		buf := make([]byte, len(src.overlap))
+		if _, err := io.ReadFull(resp.Body, buf); err != nil || !bytes.Equal(buf, src.overlap) {
+			resp.Body.Close()
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return false, err
+			}
+			src.reset()
+			return true, nil
+		}
+	}
+
+	src.etag = resp.Header.Get("ETag")
+	src.lastModified = lastModified
+	src.body = resp.Body
+	return false, nil
+}
+
+func (src *httpSource) get(ctx context.Context, byteRange string, conditional bool) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, src.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Range", byteRange)
+	// Compressed responses could not be addressed by byte ranges.
+	req.Header.Set("Accept-Encoding", "identity")
+	if conditional && src.etag != "" {
+		req.Header.Set("If-None-Match", src.etag)
+	}
+	return src.client.Do(req)
+}
+
+// parseContentRange parses "bytes <first>-<last>/<total>" and
+// "bytes */<total>". An unknown total ("*") is returned as -1.
+func parseContentRange(s string) (first, total int64, err error) {
+	invalid := fmt.Errorf("invalid Content-Range %q", s)
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, invalid
+	}
+	s = strings.TrimPrefix(s, "bytes ")
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return 0, 0, invalid
+	}
+	span, size := s[:slash], s[slash+1:]
+
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+	}
+	if span == "*" {
+		return 0, total, nil
+	}
+	dash := strings.IndexByte(span, '-')
+	if dash < 0 {
+		return 0, 0, invalid
+	}
+	if first, err = strconv.ParseInt(span[:dash], 10, 64); err != nil {
+		return 0, 0, invalid
+	}
+	return first, total, nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remoteFile serves a growing file with Range support, like a web server
+// exposing a log file.
+type remoteFile struct {
+	mu      sync.Mutex
+	data    []byte
+	modTime time.Time
+	ranges  bool
+}
+
+func (f *remoteFile) set(data string) {
+	f.mu.Lock()
+	f.data = []byte(data)
+	f.modTime = f.modTime.Add(time.Second)
+	f.mu.Unlock()
+}
+
+func (f *remoteFile) append(data string) {
+	f.mu.Lock()
+	f.data = append(f.data, data...)
+	f.modTime = f.modTime.Add(time.Second)
+	f.mu.Unlock()
+}
+
+func (f *remoteFile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	data := append([]byte(nil), f.data...)
+	modTime := f.modTime
+	f.mu.Unlock()
+
+	if !f.ranges {
+		r.Header.Del("Range")
+	}
+	http.ServeContent(w, r, "test.log", modTime, bytes.NewReader(data))
+}
+
+func startRemoteFile(t *testing.T, data string, ranges bool) (*remoteFile, *httptest.Server) {
+	f := &remoteFile{data: []byte(data), modTime: time.Unix(1e9, 0), ranges: ranges}
+	return f, httptest.NewServer(f)
+}
+
+func startURLTail(t *testing.T, url string, config Config) (*Tail, TailTest) {
+	config.PollInterval = 10 * time.Millisecond
+	config.Logger = DiscardingLogger
+	tail, err := TailURL(url, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tail, TailTest{Name: t.Name(), done: make(chan struct{}), T: t}
+}
+
+func TestTailURL(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nworld\n", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"hello", "world"}, false)
+	f.append("more\ndata\n")
+	tailTest.ReadLines(tail, []string{"more", "data"}, false)
+
+	offset, _ := tail.Tell()
+	if offset != 22 {
+		t.Errorf("expected offset 22, got %d", offset)
+	}
+}
+
+func TestTailURLTruncated(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nworld\n", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"hello", "world"}, false)
+	f.set("new\n")
+	tailTest.ReadLines(tail, []string{"new"}, false)
+}
+
+func TestTailURLReplaced(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nworld\n", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"hello", "world"}, false)
+	f.set("a larger\nreplacement\n")
+	tailTest.ReadLines(tail, []string{"a larger", "replacement"}, false)
+}
+
+func TestTailURLWithoutRanges(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nworld\n", false)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"hello", "world"}, false)
+	f.append("more\n")
+	tailTest.ReadLines(tail, []string{"more"}, false)
+}
+
+func TestTailURLCompleteLines(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nwor", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true, CompleteLines: true, MaxLineSize: 4})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"hell", "o"}, false)
+	time.Sleep(50 * time.Millisecond)
+	f.append("ld\n")
+	tailTest.ReadLines(tail, []string{"worl", "d"}, false)
+}
+
+func TestTailURLLocationEnd(t *testing.T) {
+	f, srv := startRemoteFile(t, "hello\nworld\n", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{Follow: true, Location: &SeekInfo{-6, io.SeekEnd}})
+	defer tail.Stop()
+
+	tailTest.ReadLines(tail, []string{"world"}, false)
+	f.append("more\n")
+	tailTest.ReadLines(tail, []string{"more"}, false)
+}
+
+func TestTailURLDontFollow(t *testing.T) {
+	_, srv := startRemoteFile(t, "hello\nworld", true)
+	defer srv.Close()
+	tail, tailTest := startURLTail(t, srv.URL, Config{})
+	tailTest.ReadLines(tail, []string{"hello", "world"}, false)
+	if _, ok := <-tail.Lines; ok {
+		t.Error("expected Lines to be closed")
+	}
+	if err := tail.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTailURLMustExist(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	if _, err := TailURL(srv.URL, Config{MustExist: true}); err == nil {
+		t.Error("MustExist:true is violated")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in           string
+		first, total int64
+		ok           bool
+	}{
+		{"bytes 0-99/200", 0, 200, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */42", 0, 42, true},
+		{"bytes 1-2", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+	}
+	for _, test := range tests {
+		first, total, err := parseContentRange(test.in)
+		if (err == nil) != test.ok || first != test.first || total != test.total {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", test.in, first, total, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// JSONDecoder decodes the lines of a Tail as JSON records, one per line
+// (JSON Lines). Lines split by MaxLineSize or read before they were complete
+// are joined again before they are decoded.
+type JSONDecoder struct {
+	Tail *Tail
+
+	// OnError is called with the lines that cannot be decoded, which are then
+	// skipped, and with the lines carrying an error from the tail (see
+	// Line.Err) other than a *ParseError. If nil, the errors are logged with
+	// the Logger of the Tail.
+	OnError func(line *Line, err error)
+
+	fragments strings.Builder
+}
+
+// NewJSONDecoder returns a decoder reading the lines of t. The Lines channel
+// of t must not be consumed by others.
+func NewJSONDecoder(t *Tail) *JSONDecoder {
+	return &JSONDecoder{Tail: t}
+}
+
+// TailJSON begins tailing a file of JSON records like TailFile, and returns
+// a decoder for them.
+func TailJSON(filename string, config Config) (*JSONDecoder, error) {
+	t, err := TailFile(filename, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONDecoder(t), nil
+}
+
+// Decode stores the next record in the value pointed to by v, as done by
+// json.Unmarshal, and returns the line it was decoded from. For records
+// joined from several lines, the returned Line holds the whole record and the
+// position of its last part. Empty lines are skipped.
+//
+// Once the tail has stopped, Decode returns io.EOF, or the error that made
+// the tail stop.
+func (d *JSONDecoder) Decode(v interface{}) (*Line, error) {
+	for {
+		line, ok := <-d.Tail.Lines
+		if !ok {
+			if d.fragments.Len() > 0 {
+				d.error(&Line{Text: d.fragments.String()}, io.ErrUnexpectedEOF)
+				d.fragments.Reset()
+			}
+			if err := d.Tail.Wait(); err != nil && err != errStopAtEOF {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		if _, ok := line.Err.(*ParseError); line.Err != nil && !ok {
+			d.error(line, line.Err)
+			continue
+		}
+		if line.Partial {
+			d.fragments.WriteString(line.Text)
+			continue
+		}
+		if d.fragments.Len() > 0 {
+			d.fragments.WriteString(line.Text)
+			whole := *line
+			whole.Text = d.fragments.String()
+			d.fragments.Reset()
+			line = &whole
+		}
+
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(line.Text), v); err != nil {
+			d.error(line, err)
+			continue
+		}
+		return line, nil
+	}
+}
+
+func (d *JSONDecoder) error(line *Line, err error) {
+	if d.OnError != nil {
+		d.OnError(line, err)
+		return
+	}
+	d.Tail.Logger.Printf("Skipping line %d of %s: %s", line.Num, d.Tail.Filename, err)
+}
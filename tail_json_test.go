@@ -0,0 +1,89 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+type record struct {
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+}
+
+func TestJSONDecoder(t *testing.T) {
+	tailTest, cleanup := NewTailTest("json-decoder", t)
+	defer cleanup()
+	tailTest.CreateFile("test.jsonl", `{"level":"info","msg":"started"}`+"\n"+
+		"not json\n"+
+		"\n"+
+		`{"level":"warn","msg":"this record is longer than MaxLineSize"}`+"\n")
+
+	d, err := TailJSON(tailTest.path+"/test.jsonl", Config{MaxLineSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var skipped []string
+	d.OnError = func(line *Line, err error) { skipped = append(skipped, line.Text) }
+
+	var got []record
+	var last *Line
+	for {
+		var r record
+		line, err := d.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+		last = line
+	}
+
+	if len(got) != 2 || got[0].Msg != "started" || got[1].Msg != "this record is longer than MaxLineSize" {
+		t.Errorf("unexpected records %+v", got)
+	}
+	if last.Partial || last.Text != `{"level":"warn","msg":"this record is longer than MaxLineSize"}` {
+		t.Errorf("expected the line of the whole record, got %+v", last)
+	}
+	if len(skipped) != 1 || skipped[0] != "not json" {
+		t.Errorf("expected the malformed line to be skipped, got %q", skipped)
+	}
+}
+
+func TestJSONDecoderIncompleteLine(t *testing.T) {
+	tailTest, cleanup := NewTailTest("json-decoder-incomplete", t)
+	defer cleanup()
+	tailTest.CreateFile("test.jsonl", `{"level":"info",`)
+
+	d, err := TailJSON(tailTest.path+"/test.jsonl", Config{Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.OnError = func(line *Line, err error) { t.Errorf("unexpected error for %q: %s", line.Text, err) }
+	defer d.Tail.Cleanup()
+	defer d.Tail.Stop()
+
+	done := make(chan record)
+	go func() {
+		var r record
+		if _, err := d.Decode(&r); err != nil {
+			t.Error(err)
+		}
+		done <- r
+	}()
+
+	<-time.After(100 * time.Millisecond)
+	tailTest.AppendFile("test.jsonl", `"msg":"joined"}`+"\n")
+	select {
+	case r := <-done:
+		if r.Msg != "joined" {
+			t.Errorf("unexpected record %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the record was not decoded")
+	}
+}
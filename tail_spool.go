@@ -0,0 +1,191 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/nxadm/tail/spool"
+)
+
+// Number of lines delivered from the spool between two commits, when more
+// lines are waiting. Lines delivered since the last commit are delivered
+// again if the process crashes.
+const spoolCommitLines = 100
+
+// spooledLine is a Line in the spool.
+type spooledLine struct {
+	Text      string
+	Num       int
+	Offset    int64
+	Time      time.Time
+	EventTime time.Time
+	Fields    map[string]interface{} `json:",omitempty"`
+	Partial   bool                   `json:",omitempty"`
+	Err       string                 `json:",omitempty"`
+}
+
+// Name of the file of SpoolDir holding the position in the file of the lines
+// spooled.
+const spoolSourceName = "source.json"
+
+// spoolSource is the position in the file following the last whole line
+// written to the spool, from which the next tail of SpoolDir resumes reading
+// it. Like Ack checkpoints, it is saved with the fingerprint of the file.
+type spoolSource struct {
+	checkpointer *FileCheckpointer
+	key          string
+	offset       int64
+	unsaved      int // Lines spooled since the last save
+}
+
+// openSpool opens the spool of SpoolDir. Lines are then delivered from the
+// spool by drainSpool.
+func (tail *Tail) openSpool() error {
+	if tail.Ack {
+		return errors.New("Unable to acknowledge spooled lines, Ack and SpoolDir cannot be combined")
+	}
+	s, err := spool.Open(tail.SpoolDir, spool.Options{MaxSize: tail.SpoolSize})
+	if err != nil {
+		return err
+	}
+	tail.spool = s
+	tail.spoolDone = make(chan struct{})
+	tail.spooled = &spoolSource{
+		checkpointer: NewFileCheckpointer(filepath.Join(tail.SpoolDir, spoolSourceName)),
+		key:          tail.checkpointKey(),
+	}
+	return nil
+}
+
+// saveSpoolSource saves the position in the file of the lines spooled, if
+// lines were spooled since the last save.
+func (tail *Tail) saveSpoolSource() {
+	src := tail.spooled
+	if src.unsaved == 0 || tail.file == nil || tail.Pipe {
+		return
+	}
+	c, err := fingerprint(tail.file, fingerprintSize)
+	if err == nil {
+		c.Offset = src.offset
+		err = src.checkpointer.Save(src.key, c)
+	}
+	if err != nil {
+		tail.Logger.Printf("Unable to save the spooled position of %s: %s", tail.name, err)
+		return
+	}
+	src.unsaved = 0
+}
+
+// spoolLine writes a line to the spool. It returns false if the tail is
+// stopped while the spool is full.
+func (tail *Tail) spoolLine(l *Line) bool {
+	sl := spooledLine{
+		Text:      l.Text,
+		Num:       l.Num,
+		Offset:    l.SeekInfo.Offset,
+		Time:      l.Time,
+		EventTime: l.EventTime,
+		Fields:    l.Fields,
+		Partial:   l.Partial,
+	}
+	if l.Err != nil {
+		sl.Err = l.Err.Error()
+	}
+	record, err := json.Marshal(sl)
+	if err != nil {
+		tail.Logger.Printf("Unable to spool a line of %s: %s", tail.name, err)
+		return true
+	}
+	if err := tail.spool.Write(record, tail.Dying()); err != nil {
+		if err != spool.ErrCanceled {
+			tail.Killf("Unable to spool a line of %s: %s", tail.name, err)
+		}
+		return false
+	}
+	if !l.Partial {
+		// After a crash, the lines spooled since the last save are read
+		// again.
+		tail.spooled.offset = l.SeekInfo.Offset
+		if tail.spooled.unsaved++; tail.spooled.unsaved >= spoolCommitLines {
+			tail.saveSpoolSource()
+		}
+	}
+	return true
+}
+
+// drainSpool delivers the lines of the spool on Lines, committing them in
+// the spool once delivered, until the tail is stopped or all the lines were
+// delivered, up to EOF with StopAtEOF. Lines left by a previous tail of
+// SpoolDir are delivered first.
+func (tail *Tail) drainSpool() {
+	defer close(tail.spoolDone)
+	defer close(tail.Lines)
+	defer tail.spool.Close()
+
+	delivered := 0
+	commit := func() {
+		if delivered == 0 {
+			return
+		}
+		if err := tail.spool.Commit(); err != nil {
+			tail.Logger.Printf("Unable to commit the spool of %s: %s", tail.name, err)
+		}
+		delivered = 0
+	}
+	defer commit()
+
+	// With StopAtEOF, the lines spooled up to EOF are all delivered.
+	dying := tail.Dying()
+	for {
+		record, err := tail.spool.Read(dying)
+		if err == spool.ErrCanceled && tail.Err() == errStopAtEOF {
+			dying = nil
+			continue
+		}
+		if err == io.EOF || err == spool.ErrCanceled {
+			return
+		}
+		if err != nil {
+			tail.Logger.Printf("Unable to read the spool of %s: %s", tail.name, err)
+			return
+		}
+
+		var sl spooledLine
+		if err := json.Unmarshal(record, &sl); err != nil {
+			tail.Logger.Printf("Skipping a line of the spool of %s: %s", tail.name, err)
+			continue
+		}
+		line := &Line{
+			Text:      sl.Text,
+			Num:       sl.Num,
+			SeekInfo:  SeekInfo{Offset: sl.Offset},
+			Time:      sl.Time,
+			EventTime: sl.EventTime,
+			Fields:    sl.Fields,
+			Partial:   sl.Partial,
+		}
+		if sl.Err != "" {
+			line.Err = errors.New(sl.Err)
+		}
+		select {
+		case tail.Lines <- line:
+		case <-dying:
+			if tail.Err() != errStopAtEOF {
+				// The line is delivered by the next tail of SpoolDir.
+				tail.spool.UnreadRecord()
+				return
+			}
+			dying = nil
+			tail.Lines <- line
+		}
+
+		if delivered++; delivered >= spoolCommitLines || tail.spool.Buffered() == 0 {
+			commit()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"testing"
+)
+
+func TestSpoolDir(t *testing.T) {
+	tailTest, cleanup := NewTailTest("spool", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	config := Config{Follow: true, SpoolDir: tailTest.path + "/spool", Logger: DiscardingLogger}
+	tail := tailTest.StartTail("test.txt", config)
+	defer tail.Cleanup()
+
+	// The file is read without waiting for the consumer.
+	waitForStats(t, tail, func(s Stats) bool { return s.Lines == 3 && s.Lag == 0 })
+	if line := <-tail.Lines; line.Text != "hello" || line.SeekInfo.Offset != 6 {
+		t.Errorf("unexpected line %+v", line)
+	}
+	tail.Stop()
+
+	// The lines left in the spool are delivered by the next tail, which
+	// resumes reading the file after them.
+	tailTest.AppendFile("test.txt", "more\n")
+	config.Follow = false
+	tail = tailTest.StartTail("test.txt", config)
+	var texts []string
+	for line := range tail.Lines {
+		texts = append(texts, line.Text)
+	}
+	if len(texts) != 3 || texts[0] != "world" || texts[1] != "again" || texts[2] != "more" {
+		t.Errorf("expected the spooled lines, then the new one, got %q", texts)
+	}
+	if err := tail.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpoolDirStopAtEOF(t *testing.T) {
+	tailTest, cleanup := NewTailTest("spool-stop-at-eof", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\nworld\nagain\n")
+	tail := tailTest.StartTail("test.txt", Config{Follow: true, SpoolDir: tailTest.path + "/spool", Logger: DiscardingLogger})
+	defer tail.Cleanup()
+
+	// The lines spooled before StopAtEOF are all delivered.
+	waitForStats(t, tail, func(s Stats) bool { return s.Lines == 3 && s.Lag == 0 })
+	go tail.StopAtEOF()
+	var texts []string
+	for line := range tail.Lines {
+		texts = append(texts, line.Text)
+	}
+	if len(texts) != 3 || texts[0] != "hello" || texts[1] != "world" || texts[2] != "again" {
+		t.Errorf("expected the lines up to EOF, got %q", texts)
+	}
+}
+
+func TestSpoolDirWithAck(t *testing.T) {
+	tailTest, cleanup := NewTailTest("spool-ack", t)
+	defer cleanup()
+	_, err := TailFile(tailTest.path+"/test.txt", Config{Ack: true, SpoolDir: tailTest.path + "/spool"})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
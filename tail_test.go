@@ -13,6 +13,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -153,6 +154,38 @@ func TestMaxLineSizeNoFollow(t *testing.T) {
 	maxLineSize(t, false, "hello\nworld\nfin\nhe", []string{"hel", "lo", "wor", "ld", "fin", "he"})
 }
 
+// wordsParser counts the words of lines, and fails on empty ones.
+type wordsParser struct{}
+
+func (wordsParser) Parse(text string) (map[string]interface{}, error) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no words")
+	}
+	return map[string]interface{}{"words": len(words)}, nil
+}
+
+func TestParser(t *testing.T) {
+	tailTest, cleanup := NewTailTest("parser", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello world\n\nsplit me\n")
+	tail := tailTest.StartTail("test.txt", Config{Parser: wordsParser{}, MaxLineSize: 5})
+
+	var got []string
+	for line := range tail.Lines {
+		got = append(got, fmt.Sprintf("%q:%v:%v", line.Text, line.Fields["words"], line.Err))
+	}
+	want := []string{
+		`"hello":<nil>:<nil>`, `" worl":<nil>:<nil>`, `"d":2:<nil>`,
+		`"":<nil>:unable to parse line: no words`,
+		`"split":<nil>:<nil>`, `" me":2:<nil>`,
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	tail.Cleanup()
+}
+
 func TestOver4096ByteLine(t *testing.T) {
 	tailTest, cleanup := NewTailTest("Over4096ByteLine", t)
 	defer cleanup()
@@ -293,6 +326,30 @@ func TestReOpenPolling(t *testing.T) {
 	reOpen(t, true)
 }
 
+// The file is replaced by another one while it is open, which inotify does not
+// report: MaxUnchangedStats must notice it.
+func TestReOpenMaxUnchangedStats(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("open files cannot be replaced on windows")
+	}
+	tailTest, cleanup := NewTailTest("reopen-unchanged-stats", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "hello\n")
+	tail := tailTest.StartTail("test.txt", Config{
+		Follow:            true,
+		ReOpen:            true,
+		PollInterval:      20 * time.Millisecond,
+		MaxUnchangedStats: 2,
+	})
+	go tailTest.VerifyTailOutput(tail, []string{"hello", "world"}, false)
+
+	<-time.After(100 * time.Millisecond)
+	tailTest.CreateFile("test.txt.new", "world\n")
+	tailTest.RenameFile("test.txt.new", "test.txt")
+
+	tailTest.Cleanup(tail, true)
+}
+
 // The use of polling file watcher could affect file rotation
 // (detected via renames), so test these explicitly.
 
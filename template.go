@@ -0,0 +1,89 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExpandTemplate returns the file name of template at t, replacing the
+// strftime-style directives %Y (year), %y (year without century), %m
+// (month), %d (day of the month), %j (day of the year), %H (hour), %M
+// (minute), %S (second) and %% (a percent sign). Other directives are kept.
+func ExpandTemplate(template string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			b.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(template[i])
+		}
+	}
+	return b.String()
+}
+
+// templateName returns the file name of the template for the current time.
+func templateName(template string, zone *time.Location) string {
+	if zone == nil {
+		zone = time.Local
+	}
+	return ExpandTemplate(template, time.Now().In(zone))
+}
+
+// templateWait returns the time until the next check of the template: after
+// TemplateInterval, or at midnight if sooner.
+func (tail *Tail) templateWait() time.Duration {
+	wait := tail.TemplateInterval
+	if wait <= 0 {
+		wait = time.Minute
+	}
+	zone := tail.TemplateTimeZone
+	if zone == nil {
+		zone = time.Local
+	}
+	now := time.Now().In(zone)
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, zone)
+	if untilMidnight := midnight.Sub(now); untilMidnight < wait {
+		wait = untilMidnight
+	}
+	return wait
+}
+
+// checkTemplate looks for the file the template names now, to switch to it
+// once the current one is read to its end. The name is only switched to
+// once the file exists, so that the old one is read until then.
+func (tail *Tail) checkTemplate() {
+	name := templateName(tail.template, tail.TemplateTimeZone)
+	if name == tail.name {
+		return
+	}
+	if _, err := os.Stat(name); err == nil {
+		tail.next = name
+	}
+}
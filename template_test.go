@@ -0,0 +1,68 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	at := time.Date(2026, time.February, 3, 4, 5, 6, 0, time.UTC)
+	for template, expected := range map[string]string{
+		"/var/log/app/%Y-%m-%d.log": "/var/log/app/2026-02-03.log",
+		"app.%y%j.%H%M%S":           "app.26034.040506",
+		"100%%-%Q-%":                "100%-%Q-%",
+	} {
+		if name := ExpandTemplate(template, at); name != expected {
+			t.Errorf("expected %s for %s, got %s", expected, template, name)
+		}
+	}
+}
+
+func TestFilenameTemplate(t *testing.T) {
+	testFilenameTemplate(t, "filename-template", false)
+}
+
+func TestFilenameTemplatePolling(t *testing.T) {
+	testFilenameTemplate(t, "filename-template-polling", true)
+}
+
+func testFilenameTemplate(t *testing.T, name string, poll bool) {
+	tailTest, cleanup := NewTailTest(name, t)
+	defer cleanup()
+	// A file for each of the next seconds, read in turn.
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		tailTest.CreateFile(ExpandTemplate("app-%S.log", now.Add(time.Duration(i)*time.Second)), strconv.Itoa(i)+"\n")
+	}
+	tail := tailTest.StartTail("app-%S.log", Config{
+		Follow:           true,
+		Poll:             poll,
+		PollInterval:     100 * time.Millisecond,
+		FilenameTemplate: true,
+		TemplateInterval: 100 * time.Millisecond,
+		Logger:           DiscardingLogger,
+	})
+	defer tail.Cleanup()
+	defer tail.Stop()
+
+	last := -1
+	for last < 3 {
+		select {
+		case line := <-tail.Lines:
+			n, err := strconv.Atoi(line.Text)
+			if err != nil || (last >= 0 && n != last+1) {
+				t.Fatalf("unexpected line %s after %d", line.Text, last)
+			}
+			last = n
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for the line after %d", last)
+		}
+	}
+	s := waitForStats(t, tail, func(s Stats) bool { return s.Switches > 0 })
+	if s.File != tailTest.path+"/"+ExpandTemplate("app-%S.log", now.Add(3*time.Second)) {
+		t.Errorf("unexpected file %s", s.File)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nxadm/tail/util"
+)
+
+// TimestampExtractor finds the time of the event logged by a line, from its
+// text or from the fields parsed by Config.Parser. See Config.TimestampExtractor.
+type TimestampExtractor interface {
+	Extract(text string, fields map[string]interface{}) (time.Time, bool)
+}
+
+// TimestampFallback tells which EventTime is given to the lines in which the
+// TimestampExtractor finds no timestamp.
+type TimestampFallback int
+
+const (
+	FallbackZero     TimestampFallback = iota // Leave EventTime zero
+	FallbackPrevious                          // Use the EventTime of the previous line, e.g. for stack traces
+	FallbackReadTime                          // Use the time at which the line was read (Line.Time)
+)
+
+// Named layouts known to NewTimestampExtractor, besides Go time layouts.
+var timestampLayouts = map[string]string{
+	"rfc3339":  time.RFC3339Nano,
+	"datetime": "2006-01-02 15:04:05.999999999",
+	"apache":   "02/Jan/2006:15:04:05 -0700",
+	"syslog":   time.Stamp,
+}
+
+// DefaultTimestampLayouts are used by NewTimestampExtractor when no layout is
+// given.
+var DefaultTimestampLayouts = []string{"rfc3339", "datetime", "apache", "syslog"}
+
+// LayoutExtractor is a TimestampExtractor looking for timestamps in given
+// layouts, see NewTimestampExtractor.
+type LayoutExtractor struct {
+	// Location is the time zone of the timestamps that have none. If nil,
+	// time.Local is used.
+	Location *time.Location
+	// Field is the field of Line.Fields holding the timestamp. If it holds a
+	// time.Time, that time is used, strings are searched like the text of
+	// lines. If empty, the "time" field set by the parse package is used.
+	Field string
+
+	layouts []timestampLayout
+}
+
+type timestampLayout struct {
+	layout string // Go layout, or "epoch" or "epoch_ms"
+	re     *regexp.Regexp
+}
+
+// NewTimestampExtractor returns a TimestampExtractor that finds the first
+// timestamp of a line in one of the layouts, tried in order. Layouts are
+// either Go time layouts (see the time package) or one of these names:
+//
+//	rfc3339   2026-10-17T09:30:00.123Z, with optional fractional seconds
+//	datetime  2026-10-17 09:30:00,123, with optional fractional seconds
+//	apache    17/Oct/2026:09:30:00 +0200
+//	syslog    Oct 17 09:30:00, in the year that is closest to now
+//	epoch     seconds since 1970, with optional fractional part
+//	epoch_ms  milliseconds since 1970
+//
+// Without layouts, DefaultTimestampLayouts are used.
+func NewTimestampExtractor(layouts ...string) (*LayoutExtractor, error) {
+	if len(layouts) == 0 {
+		layouts = DefaultTimestampLayouts
+	}
+	e := &LayoutExtractor{}
+	for _, layout := range layouts {
+		if layout == "" {
+			return nil, fmt.Errorf("Unable to use an empty timestamp layout")
+		}
+		var expr string
+		switch layout {
+		case "epoch":
+			expr = `\b\d{10}(?:\.\d{1,9})?\b`
+		case "epoch_ms":
+			expr = `\b\d{13}\b`
+		default:
+			if named, ok := timestampLayouts[layout]; ok {
+				layout = named
+			}
+			expr = layoutRegexp(layout)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to use timestamp layout %q: %s", layout, err)
+		}
+		e.layouts = append(e.layouts, timestampLayout{layout, re})
+	}
+	return e, nil
+}
+
+// Extract returns the first timestamp found in the field or text of a line.
+func (e *LayoutExtractor) Extract(text string, fields map[string]interface{}) (time.Time, bool) {
+	field := e.Field
+	if field == "" {
+		field = "time"
+	}
+	switch v := fields[field].(type) {
+	case time.Time:
+		return v, true
+	case string:
+		if t, ok := e.find(v); ok {
+			return t, true
+		}
+	}
+	return e.find(text)
+}
+
+func (e *LayoutExtractor) find(text string) (time.Time, bool) {
+	loc := e.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, l := range e.layouts {
+		for _, s := range l.re.FindAllString(text, 2) {
+			if t, ok := l.parse(s, loc); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (l timestampLayout) parse(s string, loc *time.Location) (time.Time, bool) {
+	switch l.layout {
+	case "epoch":
+		secs, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)).In(loc), true
+	case "epoch_ms":
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).In(loc), true
+	}
+
+	t, err := time.ParseInLocation(l.layout, s, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 {
+		t = util.ClosestYear(t, time.Now())
+	}
+	return t, true
+}
+
+// layoutElements maps the elements of Go time layouts to regular expressions
+// matching them, longest elements first.
+var layoutElements = []struct {
+	element, expr string
+}{
+	{"January", `(?:January|February|March|April|May|June|July|August|September|October|November|December)`},
+	{"Monday", `(?:Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday)`},
+	{"Z07:00:00", `(?:Z|[+-]\d{2}:\d{2}:\d{2})`},
+	{"-07:00:00", `[+-]\d{2}:\d{2}:\d{2}`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`},
+	{"-07:00", `[+-]\d{2}:\d{2}`},
+	{"Z0700", `(?:Z|[+-]\d{4})`},
+	{"-0700", `[+-]\d{4}`},
+	{"2006", `\d{4}`},
+	{"Z07", `(?:Z|[+-]\d{2})`},
+	{"-07", `[+-]\d{2}`},
+	{"Jan", `(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)`},
+	{"Mon", `(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun)`},
+	{"MST", `[A-Z]{3,5}`},
+	{"__2", `[ \d]{2}\d`},
+	{"002", `\d{3}`},
+	{"_2", `[ \d]\d`},
+	{"01", `\d{2}`},
+	{"02", `\d{2}`},
+	{"03", `\d{2}`},
+	{"04", `\d{2}`},
+	{"05", `\d{2}`},
+	{"06", `\d{2}`},
+	{"15", `\d{2}`},
+	{"PM", `[AP]M`},
+	{"pm", `[ap]m`},
+	{"1", `\d{1,2}`},
+	{"2", `\d{1,2}`},
+	{"3", `\d{1,2}`},
+	{"4", `\d{1,2}`},
+	{"5", `\d{1,2}`},
+}
+
+var fractionRegexp = regexp.MustCompile(`^[.,](0+|9+)(?:\D|$)`)
+
+// layoutRegexp returns a regular expression matching the timestamps in the
+// Go time layout.
+func layoutRegexp(layout string) string {
+	var b strings.Builder
+	if startsWithWord(layout) {
+		b.WriteString(`\b`)
+	}
+	for i := 0; i < len(layout); {
+		if m := fractionRegexp.FindStringSubmatch(layout[i:]); m != nil {
+			if m[1][0] == '0' {
+				b.WriteString(`[.,]\d{` + strconv.Itoa(len(m[1])) + `}`)
+			} else {
+				b.WriteString(`(?:[.,]\d+)?`)
+			}
+			i += 1 + len(m[1])
+			continue
+		}
+
+		matched := false
+		for _, e := range layoutElements {
+			if strings.HasPrefix(layout[i:], e.element) {
+				b.WriteString(e.expr)
+				i += len(e.element)
+				matched = true
+				// When parsing, fractional seconds are accepted after the
+				// seconds even if the layout has none.
+				if (e.element == "05" || e.element == "5") && !fractionRegexp.MatchString(layout[i:]) {
+					b.WriteString(`(?:[.,]\d+)?`)
+				}
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+	if startsWithWord(layout[len(layout)-1:]) {
+		b.WriteString(`\b`)
+	}
+	return b.String()
+}
+
+func startsWithWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package tail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampExtractor(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*3600)
+	e, err := NewTimestampExtractor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Location = paris
+
+	tests := map[string]time.Time{
+		"2026-10-17T09:30:00.123456Z level=info":                time.Date(2026, 10, 17, 9, 30, 0, 123456000, time.UTC),
+		"[2026-10-17T09:30:00+02:00] started":                   time.Date(2026, 10, 17, 7, 30, 0, 0, time.UTC),
+		"2026-10-17 09:30:00,250 INFO [main] started":           time.Date(2026, 10, 17, 7, 30, 0, 250000000, time.UTC),
+		`::1 - - [17/Oct/2026:09:30:00 -0700] "GET / HTTP/1.1"`: time.Date(2026, 10, 17, 16, 30, 0, 0, time.UTC),
+	}
+	for text, want := range tests {
+		got, ok := e.Extract(text, nil)
+		if !ok || !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s (%v)", text, want, got, ok)
+		}
+	}
+
+	if got, ok := e.Extract("Oct  7 09:30:00 box app: started", nil); !ok || got.Month() != time.October || got.Day() != 7 ||
+		got.Year() < 2026 || got.Location() != paris {
+		t.Errorf("unexpected syslog time %s", got)
+	}
+	if _, ok := e.Extract("no timestamp here 12:00", nil); ok {
+		t.Error("expected no timestamp")
+	}
+
+	// Fields parsed beforehand take precedence.
+	parsed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, _ := e.Extract("2026-10-17T09:30:00Z", map[string]interface{}{"time": parsed}); !got.Equal(parsed) {
+		t.Errorf("expected the parsed time, got %s", got)
+	}
+}
+
+func TestTimestampLayouts(t *testing.T) {
+	e, err := NewTimestampExtractor("epoch_ms", "epoch", "Mon Jan _2 15:04:05 MST 2006", "02.01.2006 15:04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Location = time.UTC
+	tests := map[string]time.Time{
+		"ts=1792229400123 id=42":                  time.Unix(1792229400, 123000000),
+		"ts=1792229400.5":                         time.Unix(1792229400, 500000000),
+		"Sat Oct 17 09:30:00 UTC 2026 app booted": time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+		"at 17.10.2026 09:30 sharp":               time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+	}
+	for text, want := range tests {
+		got, ok := e.Extract(text, nil)
+		if !ok || !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s (%v)", text, want, got, ok)
+		}
+	}
+	if _, ok := e.Extract("id=17922294001234", nil); ok {
+		t.Error("longer numbers are not epoch timestamps")
+	}
+}
+
+type eventExtractor struct{}
+
+func (eventExtractor) Extract(text string, fields map[string]interface{}) (time.Time, bool) {
+	t, err := time.Parse("15:04", text[:5])
+	return t, err == nil
+}
+
+func TestEventTimeFallback(t *testing.T) {
+	tailTest, cleanup := NewTailTest("event-time", t)
+	defer cleanup()
+	tailTest.CreateFile("test.txt", "09:30 panic\n  at main.go:12\n09:31 ok\n")
+
+	for _, fallback := range []TimestampFallback{FallbackZero, FallbackPrevious, FallbackReadTime} {
+		tail := tailTest.StartTail("test.txt", Config{TimestampExtractor: eventExtractor{}, TimestampFallback: fallback})
+		var got []*Line
+		for line := range tail.Lines {
+			got = append(got, line)
+		}
+		if len(got) != 3 || got[0].EventTime.Minute() != 30 || got[2].EventTime.Minute() != 31 {
+			t.Fatalf("unexpected lines %+v", got)
+		}
+		switch ev := got[1].EventTime; fallback {
+		case FallbackZero:
+			if !ev.IsZero() {
+				t.Errorf("expected no event time, got %s", ev)
+			}
+		case FallbackPrevious:
+			if !ev.Equal(got[0].EventTime) {
+				t.Errorf("expected the previous event time, got %s", ev)
+			}
+		case FallbackReadTime:
+			if !ev.Equal(got[1].Time) {
+				t.Errorf("expected the read time, got %s", ev)
+			}
+		}
+	}
+}
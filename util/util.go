@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"time"
 )
 
 type Logger struct {
@@ -47,3 +48,23 @@ func PartitionString(s string, chunkSize int) []string {
 	}
 	return parts
 }
+
+// ClosestYear sets the year of t, parsed from a timestamp without one, to the
+// year that makes it the closest to now.
+func ClosestYear(t, now time.Time) time.Time {
+	best := t
+	for _, year := range []int{now.Year() - 1, now.Year(), now.Year() + 1} {
+		c := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if best.Year() == 0 || absDuration(c.Sub(now)) < absDuration(best.Sub(now)) {
+			best = c
+		}
+	}
+	return best
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
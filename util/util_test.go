@@ -0,0 +1,16 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClosestYear(t *testing.T) {
+	now := time.Date(2027, 1, 1, 0, 10, 0, 0, time.UTC)
+	ts, _ := time.Parse(time.Stamp, "Dec 31 23:59:00")
+	if got := ClosestYear(ts, now); got.Year() != 2026 {
+		t.Errorf("expected 2026, got %s", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2019 FOSS contributors of https://github.com/nxadm/tail
+
+package watch
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EntryWatcher watches the directory entry of a file rather than the file,
+// to notice a symlink being repointed, which changes neither the old nor the
+// new target.
+type EntryWatcher struct {
+	Filename string
+	Changed  chan bool // Channel to get notified of the entry being (re)created
+
+	events <-chan fsnotify.Event
+}
+
+// WatchEntry begins watching the entry of filename in its parent directory.
+// Close must be called to stop it.
+func WatchEntry(filename string) (*EntryWatcher, error) {
+	filename = filepath.Clean(filename)
+	if err := WatchCreate(filename); err != nil {
+		return nil, err
+	}
+	w := &EntryWatcher{Filename: filename, Changed: make(chan bool, 1), events: Events(filename)}
+
+	go func() {
+		for evt := range w.events {
+			if evt.Op&fsnotify.Create == fsnotify.Create {
+				sendOnlyIfEmpty(w.Changed)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Close stops watching the entry. Once it returns, filename can be watched
+// again.
+func (w *EntryWatcher) Close() error {
+	return remove(&watchInfo{
+		op:     fsnotify.Create,
+		fname:  w.Filename,
+		events: w.events,
+	})
+}
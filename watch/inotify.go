@@ -85,11 +85,11 @@ func (fw *InotifyFileWatcher) ChangeEvents(t *tomb.Tomb, pos int64) (*FileChange
 			select {
 			case evt, ok = <-events:
 				if !ok {
-					RemoveWatch(fw.Filename)
+					// The watch was removed by RemoveWatch.
 					return
 				}
 			case <-t.Dying():
-				RemoveWatch(fw.Filename)
+				removeWatchEvents(fw.Filename, events)
 				return
 			}
 
@@ -98,7 +98,7 @@ func (fw *InotifyFileWatcher) ChangeEvents(t *tomb.Tomb, pos int64) (*FileChange
 				fallthrough
 
 			case evt.Op&fsnotify.Rename == fsnotify.Rename:
-				RemoveWatch(fw.Filename)
+				removeWatchEvents(fw.Filename, events)
 				changes.NotifyDeleted()
 				return
 
@@ -110,7 +110,7 @@ func (fw *InotifyFileWatcher) ChangeEvents(t *tomb.Tomb, pos int64) (*FileChange
 				fi, err := os.Stat(fw.Filename)
 				if err != nil {
 					if os.IsNotExist(err) {
-						RemoveWatch(fw.Filename)
+						removeWatchEvents(fw.Filename, events)
 						changes.NotifyDeleted()
 						return
 					}
@@ -30,6 +30,9 @@ type InotifyTracker struct {
 type watchInfo struct {
 	op    fsnotify.Op
 	fname string
+
+	// When set, the watch is only removed if it still delivers to events.
+	events <-chan fsnotify.Event
 }
 
 func (this *watchInfo) isCreate() bool {
@@ -58,6 +61,16 @@ var (
 	logger = log.New(os.Stderr, "", log.LstdFlags)
 )
 
+// stale reports whether winfo refers to a watch that was removed already.
+// shared.mux must be held.
+func (winfo *watchInfo) stale() bool {
+	if winfo.events == nil {
+		return false
+	}
+	ch := shared.chans[winfo.fname]
+	return ch == nil || (<-chan fsnotify.Event)(ch) != winfo.events
+}
+
 // Watch signals the run goroutine to begin watching the input filename.
 func Watch(fname string) error {
 	return watch(&watchInfo{
@@ -98,12 +111,25 @@ func RemoveWatchCreate(fname string) error {
 	})
 }
 
+// removeWatchEvents removes the watch for the input filename, unless it was
+// removed already and events is no longer the channel it delivers to.
+func removeWatchEvents(fname string, events <-chan fsnotify.Event) error {
+	return remove(&watchInfo{
+		fname:  fname,
+		events: events,
+	})
+}
+
 func remove(winfo *watchInfo) error {
 	// start running the shared InotifyTracker if not already running
 	once.Do(goRun)
 
 	winfo.fname = filepath.Clean(winfo.fname)
 	shared.mux.Lock()
+	if winfo.stale() {
+		shared.mux.Unlock()
+		return nil
+	}
 	done := shared.done[winfo.fname]
 	if done != nil {
 		delete(shared.done, winfo.fname)
@@ -164,6 +190,10 @@ func (shared *InotifyTracker) addWatch(winfo *watchInfo) error {
 // corresponding events channel.
 func (shared *InotifyTracker) removeWatch(winfo *watchInfo) error {
 	shared.mux.Lock()
+	if winfo.stale() {
+		shared.mux.Unlock()
+		return nil
+	}
 
 	ch := shared.chans[winfo.fname]
 	if ch != nil {
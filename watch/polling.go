@@ -17,15 +17,23 @@ import (
 type PollingFileWatcher struct {
 	Filename string
 	Size     int64
+	Interval time.Duration // Time between polls. If zero, POLL_DURATION is used
 }
 
 func NewPollingFileWatcher(filename string) *PollingFileWatcher {
-	fw := &PollingFileWatcher{filename, 0}
+	fw := &PollingFileWatcher{Filename: filename}
 	return fw
 }
 
 var POLL_DURATION time.Duration
 
+func (fw *PollingFileWatcher) interval() time.Duration {
+	if fw.Interval > 0 {
+		return fw.Interval
+	}
+	return POLL_DURATION
+}
+
 func (fw *PollingFileWatcher) BlockUntilExists(t *tomb.Tomb) error {
 	for {
 		if _, err := os.Stat(fw.Filename); err == nil {
@@ -34,7 +42,7 @@ func (fw *PollingFileWatcher) BlockUntilExists(t *tomb.Tomb) error {
 			return err
 		}
 		select {
-		case <-time.After(POLL_DURATION):
+		case <-time.After(fw.interval()):
 			continue
 		case <-t.Dying():
 			return tomb.ErrDying
@@ -65,7 +73,7 @@ func (fw *PollingFileWatcher) ChangeEvents(t *tomb.Tomb, pos int64) (*FileChange
 			default:
 			}
 
-			time.Sleep(POLL_DURATION)
+			time.Sleep(fw.interval())
 			fi, err := os.Stat(fw.Filename)
 			if err != nil {
 				// Windows cannot delete a file if a handle is still open (tail keeps one open)